@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/yantay0/url-shortener/internal/config"
+	"github.com/yantay0/url-shortener/internal/lib/logger/jsonlog"
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+	"github.com/yantay0/url-shortener/internal/storage/postgres"
+	"github.com/yantay0/url-shortener/internal/storage/sqlite"
+)
+
+const grantRoleUsage = `usage: url-shortener grant-role EMAIL ROLE
+
+Grants a role, e.g. admin, to the registered user with the email. Used to bootstrap the first
+admin, who can then manage the roles of other users through the API.
+`
+
+// runGrantRole implements the "grant-role" subcommand.
+func runGrantRole(cfg *config.Config, logger *jsonlog.Logger, args []string) error {
+	flags := flag.NewFlagSet("grant-role", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), grantRoleUsage) }
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	email, role := flags.Arg(0), flags.Arg(1)
+
+	var store storage.Storage
+
+	switch cfg.DB.Driver {
+	case "postgres":
+		db, err := postgres.OpenDB(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		store = postgres.New(db)
+	case "sqlite":
+		db, err := sqlite.OpenDB(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		store = sqlite.New(db)
+	default:
+		return fmt.Errorf("roles can't be granted with the %q driver, nothing is persisted", cfg.DB.Driver)
+	}
+
+	user, err := store.Users.GetByEmail(model.NormalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return fmt.Errorf("no user with the email %q, register first", email)
+		}
+		return err
+	}
+
+	err = store.Permissions.AddRoleForUser(user.ID, role)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+		return err
+	}
+
+	// Running instances pick the role up once their cached permissions expire.
+	logger.PrintInfo("granted role", map[string]string{
+		"user_id":   strconv.FormatInt(user.ID, 10),
+		"role":      role,
+		"activated": strconv.FormatBool(user.Activated),
+	})
+
+	return nil
+}
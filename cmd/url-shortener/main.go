@@ -1,15 +1,25 @@
 package main
 
 import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 
 	api "github.com/yantay0/url-shortener/internal/api"
+	"github.com/yantay0/url-shortener/internal/cache"
 	"github.com/yantay0/url-shortener/internal/config"
+	"github.com/yantay0/url-shortener/internal/dnsverify"
 	"github.com/yantay0/url-shortener/internal/mailer"
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/safety"
 	"github.com/yantay0/url-shortener/internal/storage"
 
 	"github.com/yantay0/url-shortener/internal/lib/logger/jsonlog"
+	"github.com/yantay0/url-shortener/internal/storage/memory"
 	"github.com/yantay0/url-shortener/internal/storage/postgres"
+	"github.com/yantay0/url-shortener/internal/storage/sqlite"
 )
 
 // @title URL-shortener
@@ -27,18 +37,127 @@ func main() {
 
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 
-	db, err := postgres.OpenDB(cfg)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(cfg, logger, os.Args[2:])
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "grant-role" {
+		err := runGrantRole(cfg, logger, os.Args[2:])
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		return
+	}
+
+	var store storage.Storage
+
+	switch cfg.DB.Driver {
+	case "memory":
+		store = memory.New()
+		logger.PrintInfo("using in-memory storage, nothing will be persisted", nil)
+	case "postgres":
+		db, err := postgres.OpenDB(cfg)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+
+		defer db.Close()
+		logger.PrintInfo("database conntection pool established", nil)
+
+		store = postgres.New(db)
+	case "sqlite":
+		db, err := sqlite.OpenDB(cfg)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+
+		defer db.Close()
+		logger.PrintInfo("sqlite database opened", map[string]string{"dsn": cfg.DB.Dsn})
+
+		store = sqlite.New(db)
+	default:
+		logger.PrintFatal(fmt.Errorf("unknown db driver %q", cfg.DB.Driver), nil)
+	}
+
+	shorteningsCache := cache.New[string, model.Shortening](cfg.Cache.Size, cfg.Cache.TTL)
+
+	// Publish the cache hit and miss counters on GET /debug/vars.
+	expvar.Publish("shortenings_cache", expvar.Func(func() any {
+		return shorteningsCache.Stats()
+	}))
+
+	store.Shortenings = storage.NewCachedShorteningsStorage(store.Shortenings, shorteningsCache)
+
+	permissionsCache := cache.New[int64, model.Permissions](cfg.Cache.PermissionsSize, cfg.Cache.PermissionsTTL)
+
+	expvar.Publish("permissions_cache", expvar.Func(func() any {
+		return permissionsCache.Stats()
+	}))
+
+	store.Permissions = storage.NewCachedPermissionsStorage(store.Permissions, permissionsCache)
+
+	domainsCache := cache.New[string, *model.Domain](cfg.Cache.DomainsSize, cfg.Cache.DomainsTTL)
+
+	expvar.Publish("domains_cache", expvar.Func(func() any {
+		return domainsCache.Stats()
+	}))
+
+	store.Domains = storage.NewCachedDomainsStorage(store.Domains, domainsCache, shorteningsCache)
+
+	identifiers, err := model.NewIdentifierGenerator(cfg.Identifier.Strategy, cfg.Identifier.Length, store.Shortenings)
 	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
 
-	defer db.Close()
-	logger.PrintInfo("database conntection pool established", nil)
+	checker, err := newURLChecker(cfg, logger)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
 
-	app := api.NewApp(*cfg, logger, storage.New(db), mailer.New(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.Sender))
+	verifier := dnsverify.New(net.DefaultResolver, cfg.Domains.VerificationPrefix)
+
+	app := api.NewApp(*cfg, logger, store, mailer.New(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.Sender), identifiers, checker, verifier)
 
 	err = app.Serve()
 	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
 }
+
+// newURLChecker chains the configured safety checks. It returns nil without any configured check,
+// URLs are then left unchecked.
+func newURLChecker(cfg *config.Config, logger *jsonlog.Logger) (safety.URLChecker, error) {
+	var chain safety.Chain
+
+	if cfg.Safety.BlocklistPath != "" {
+		blocklist, err := safety.NewBlocklist(cfg.Safety.BlocklistPath)
+		if err != nil {
+			return nil, err
+		}
+
+		go blocklist.Watch(cfg.Safety.ReloadInterval, func(err error) {
+			logger.PrintError(err, map[string]string{"blocklist": cfg.Safety.BlocklistPath})
+		})
+
+		chain = append(chain, blocklist)
+	}
+
+	if cfg.Safety.SafeBrowsingKey != "" {
+		chain = append(chain, safety.LookupChecker{
+			Lookup: safety.SafeBrowsing{
+				APIKey: cfg.Safety.SafeBrowsingKey,
+				Client: &http.Client{Timeout: cfg.Safety.Timeout},
+			},
+		})
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/config"
+	"github.com/yantay0/url-shortener/internal/lib/logger/jsonlog"
+	"github.com/yantay0/url-shortener/internal/storage/migrate"
+	"github.com/yantay0/url-shortener/internal/storage/postgres"
+	"github.com/yantay0/url-shortener/internal/storage/sqlite"
+	"github.com/yantay0/url-shortener/migrations"
+)
+
+const migrateUsage = `usage: url-shortener migrate <command>
+
+commands:
+  up              apply all pending migrations
+  down [N]        revert the last N applied migrations (default 1)
+  status          print the current version and the pending migrations
+  force VERSION   set the version without running migrations and clear the dirty flag
+`
+
+// runMigrate implements the "migrate" subcommand with the migrations embedded in the binary.
+func runMigrate(cfg *config.Config, logger *jsonlog.Logger, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	timeout := flags.Duration("timeout", 5*time.Minute, "maximum time to wait for the lock and run the migrations")
+	flags.Usage = func() { fmt.Fprint(flags.Output(), migrateUsage) }
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var (
+		db  *sql.DB
+		dir string
+		err error
+	)
+
+	switch cfg.DB.Driver {
+	case "postgres":
+		db, err = postgres.OpenDB(cfg)
+		dir = "."
+	case "sqlite":
+		db, err = sqlite.OpenDB(cfg)
+		dir = "sqlite"
+	default:
+		return fmt.Errorf("migrations are not supported for the %q driver", cfg.DB.Driver)
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, cfg.DB.Driver, migrations.FS, dir)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch command := flags.Arg(0); command {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, migration := range applied {
+			logMigration(logger, "applied migration", migration)
+		}
+		return ignoreNoChange(logger, err)
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			steps, err = strconv.Atoi(flags.Arg(1))
+			if err != nil || steps < 1 {
+				return errors.New("down expects a positive number of steps")
+			}
+		}
+
+		reverted, err := m.Down(ctx, steps)
+		for _, migration := range reverted {
+			logMigration(logger, "reverted migration", migration)
+		}
+		return ignoreNoChange(logger, err)
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("version: %d, dirty: %t\n", status.Version, status.Dirty)
+		for _, migration := range status.Migrations {
+			state := "pending"
+			if migration.Version <= status.Version {
+				state = "applied"
+			}
+			fmt.Printf("%06d_%s\t%s\n", migration.Version, migration.Name, state)
+		}
+		return nil
+	case "force":
+		if flags.NArg() < 2 {
+			return errors.New("force expects a version")
+		}
+
+		version, err := strconv.ParseInt(flags.Arg(1), 10, 64)
+		if err != nil || version < migrate.NilVersion {
+			return errors.New("force expects a version of -1 or greater")
+		}
+
+		err = m.Force(ctx, version)
+		if err != nil {
+			return err
+		}
+
+		logger.PrintInfo("forced migration version", map[string]string{"version": flags.Arg(1)})
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+}
+
+func logMigration(logger *jsonlog.Logger, message string, migration *migrate.Migration) {
+	logger.PrintInfo(message, map[string]string{
+		"version": strconv.FormatInt(migration.Version, 10),
+		"name":    migration.Name,
+	})
+}
+
+func ignoreNoChange(logger *jsonlog.Logger, err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		logger.PrintInfo("no change", nil)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+	"github.com/yantay0/url-shortener/internal/validator"
+)
+
+// createAPIKeyHandler creates a named, long-lived key for scripts and services. The plaintext key
+// is only returned in this response.
+func (app *App) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string     `json:"name"`
+		Permissions []string   `json:"permissions"`
+		Expiry      *time.Time `json:"expiry"` // The key doesn't expire if it's missing.
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	token, err := model.GenerateToken(user.ID, 0, storage.ScopeAPIKey)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	token.Name = input.Name
+	token.Expiry = input.Expiry
+	token.Permissions = input.Permissions
+
+	// Compared against the permissions of the request, so a key can't grant more than the key
+	// it was created with.
+	v := validator.New()
+	if model.ValidateAPIKey(v, token, app.contextGetPermissions(r)); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.Storage.Tokens.Insert(token)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"api_key": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *App) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	tokens, err := app.Storage.Tokens.GetAllForUser(storage.ScopeAPIKey, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"api_keys": tokens}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *App) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.Storage.Tokens.DeleteForUser(storage.ScopeAPIKey, id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "api key successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
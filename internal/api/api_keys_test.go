@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+func TestAccountRoutesRejectAPIKeys(t *testing.T) {
+	app := newTestApp(t)
+
+	user, token := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+	// Even a key with every permission of its user mustn't manage the account.
+	key := newTestAPIKey(t, app, user, "shortenings:read", "shortenings:write", "shortenings:admin", "permissions:read", "permissions:write")
+
+	domain, err := model.NewDomain(user.ID, "go.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.Storage.Domains.Insert(domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/api/v1/api-keys", ""},
+		{http.MethodPost, "/api/v1/api-keys", `{"name": "ci", "permissions": ["shortenings:read"]}`},
+		{http.MethodDelete, "/api/v1/api-keys/1", ""},
+		{http.MethodGet, "/api/v1/domains", ""},
+		{http.MethodPost, "/api/v1/domains", `{"host": "links.example.com"}`},
+		{http.MethodPost, fmt.Sprintf("/api/v1/domains/%d/verify", domain.ID), ""},
+		{http.MethodDelete, fmt.Sprintf("/api/v1/domains/%d", domain.ID), ""},
+	}
+
+	for _, route := range routes {
+		w := request(t, app, route.method, route.path, "ApiKey "+key, route.body)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s with an API key: got status %d, want 403", route.method, route.path, w.Code)
+		}
+	}
+
+	// The same requests pass with a session token.
+	w := request(t, app, http.MethodGet, "/api/v1/api-keys", bearer(token), "")
+	checkStatus(t, w, http.StatusOK)
+
+	w = request(t, app, http.MethodPost, "/api/v1/api-keys", bearer(token), `{"name": "ci", "permissions": ["shortenings:read"]}`)
+	checkStatus(t, w, http.StatusCreated)
+
+	w = request(t, app, http.MethodGet, "/api/v1/domains", bearer(token), "")
+	checkStatus(t, w, http.StatusOK)
+
+	w = request(t, app, http.MethodDelete, fmt.Sprintf("/api/v1/domains/%d", domain.ID), bearer(token), "")
+	checkStatus(t, w, http.StatusOK)
+}
+
+func TestRestrictedAPIKeyCanUseItsPermissions(t *testing.T) {
+	app := newTestApp(t)
+
+	user, _ := newTestUser(t, app, "editor@example.com", model.RoleEditor)
+	key := newTestAPIKey(t, app, user, "shortenings:read")
+
+	w := request(t, app, http.MethodGet, fmt.Sprintf("/api/v1/users/%d/shortenings", user.ID), "ApiKey "+key, "")
+	checkStatus(t, w, http.StatusOK)
+
+	w = request(t, app, http.MethodGet, "/api/v1/api-keys", "ApiKey "+key, "")
+	checkStatus(t, w, http.StatusForbidden)
+}
+
+func TestDeleteAPIKeyWithInvalidID(t *testing.T) {
+	app := newTestApp(t)
+	_, token := newTestUser(t, app, "user@example.com", model.RoleEditor)
+
+	w := request(t, app, http.MethodDelete, "/api/v1/api-keys/abc", bearer(token), "")
+	checkStatus(t, w, http.StatusBadRequest)
+
+	w = request(t, app, http.MethodDelete, "/api/v1/api-keys/12345", bearer(token), "")
+	checkStatus(t, w, http.StatusNotFound)
+}
@@ -2,8 +2,14 @@ package api
 
 import (
 	"github.com/yantay0/url-shortener/internal/config"
+	"github.com/yantay0/url-shortener/internal/dnsverify"
 	"github.com/yantay0/url-shortener/internal/lib/logger/jsonlog"
+	"github.com/yantay0/url-shortener/internal/linkcheck"
 	"github.com/yantay0/url-shortener/internal/mailer"
+	"github.com/yantay0/url-shortener/internal/metadata"
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/recorder"
+	"github.com/yantay0/url-shortener/internal/safety"
 	"github.com/yantay0/url-shortener/internal/storage"
 )
 
@@ -12,13 +18,37 @@ type App struct {
 	Logger  *jsonlog.Logger
 	Storage storage.Storage
 	Mailer  mailer.Mailer
+	// Identifiers generates the identifiers of shortenings created without a custom alias.
+	Identifiers model.IdentifierGenerator
+	// Safety checks original URLs for malicious and phishing destinations, nil if no check is
+	// configured.
+	Safety safety.URLChecker
+	// Verifier checks the DNS records that prove control over custom domains.
+	Verifier *dnsverify.Verifier
+
+	unlockAttempts *attemptLimiter
+	clicks         *recorder.Recorder
+	ipHasher       model.IPHasher
+	destinations   *linkcheck.Checker
+	previews       *metadata.Fetcher
 }
 
-func NewApp(cfg config.Config, logger *jsonlog.Logger, storage storage.Storage, mailer mailer.Mailer) *App {
+func NewApp(cfg config.Config, logger *jsonlog.Logger, storage storage.Storage, mailer mailer.Mailer, identifiers model.IdentifierGenerator, checker safety.URLChecker, verifier *dnsverify.Verifier) *App {
 	return &App{
-		Config:  cfg,
-		Logger:  logger,
-		Storage: storage,
-		Mailer:  mailer,
+		Config:      cfg,
+		Logger:      logger,
+		Storage:     storage,
+		Mailer:      mailer,
+		Identifiers: identifiers,
+		Safety:      checker,
+		Verifier:    verifier,
+
+		unlockAttempts: newAttemptLimiter(cfg.Limiter.UnlockRPS, cfg.Limiter.UnlockBurst),
+		clicks: recorder.New(storage.Clicks, logger, cfg.Recorder.Workers, cfg.Recorder.BufferSize,
+			cfg.Recorder.BatchSize, cfg.Recorder.FlushInterval),
+		ipHasher: model.IPHasher{Secret: []byte(cfg.Recorder.IPHashSecret), Rotation: cfg.Recorder.IPHashRotation},
+		destinations: linkcheck.New(storage.Shortenings, logger, cfg.LinkCheck.Concurrency, cfg.LinkCheck.BatchSize,
+			cfg.LinkCheck.Interval, cfg.LinkCheck.Timeout, cfg.LinkCheck.MaxBackoff),
+		previews: metadata.NewFetcher(cfg.Preview.Timeout, cfg.Preview.MaxBytes),
 	}
 }
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/yantay0/url-shortener/internal/config"
+	"github.com/yantay0/url-shortener/internal/lib/logger/jsonlog"
+	"github.com/yantay0/url-shortener/internal/mailer"
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+	"github.com/yantay0/url-shortener/internal/storage/memory"
+)
+
+// newTestApp returns an app on the in-memory storage with the default configuration. Nothing
+// reaches the network: the safety checks, link previews and rate limits are off.
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+
+	var cfg config.Config
+	err := cleanenv.ReadEnv(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.HTTPServer.PublicBaseURL = "http://localhost:4000"
+	cfg.Limiter.Enabled = false
+	cfg.Preview.Enabled = false
+
+	identifiers, err := model.NewIdentifierGenerator(cfg.Identifier.Strategy, cfg.Identifier.Length, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := jsonlog.New(io.Discard, jsonlog.LevelInfo)
+
+	return NewApp(cfg, logger, memory.New(), mailer.Mailer{}, identifiers, nil, nil)
+}
+
+// newTestUser inserts an activated user with the role and returns it with an authentication
+// token. The user has no usable password, hashing one would slow the tests down.
+func newTestUser(t *testing.T, app *App, email, role string) (*model.User, string) {
+	t.Helper()
+
+	user := &model.User{Name: "Test", Email: email, Activated: true}
+	user.Password.Hash = []byte("-")
+
+	err := app.Storage.Users.Insert(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.Storage.Permissions.AddRoleForUser(user.ID, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := app.Storage.Tokens.New(user.ID, time.Hour, storage.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user, token.Plaintext
+}
+
+// request sends a request with the JSON body through all routes. An empty authorization is
+// sent anonymously.
+func request(t *testing.T, app *App, method, path, authorization, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+
+	w := httptest.NewRecorder()
+	app.Routes().ServeHTTP(w, r)
+
+	return w
+}
+
+// bearer returns the Authorization header value of an authentication token.
+func bearer(token string) string {
+	return "Bearer " + token
+}
+
+// checkStatus fails the test if the response doesn't have the wanted status.
+func checkStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+
+	if w.Code != want {
+		t.Errorf("got status %d %s, want %d; body: %s", w.Code, http.StatusText(w.Code), want, w.Body)
+	}
+}
+
+// newTestAPIKey inserts an API key of the user restricted to the permissions and returns its
+// plaintext.
+func newTestAPIKey(t *testing.T, app *App, user *model.User, permissions ...string) string {
+	t.Helper()
+
+	token, err := model.GenerateToken(user.ID, 0, storage.ScopeAPIKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token.Name = "test"
+	token.Permissions = permissions
+
+	err = app.Storage.Tokens.Insert(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token.Plaintext
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tomasen/realip"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+	"github.com/yantay0/url-shortener/internal/validator"
+)
+
+// recordClick queues the analytics event of a redirect for the click recorder. Failing to record
+// a click must not break the redirect itself, so errors are only logged. counted tells the
+// recorder that the visit was already counted on redirect.
+func (app *App) recordClick(r *http.Request, shortening *model.Shortening, counted bool) {
+	family, device := model.ParseUserAgent(r.UserAgent())
+
+	now := time.Now()
+
+	click := &model.Click{
+		Identifier: shortening.Identifier,
+		Domain:     shortening.Domain,
+		ClickedAt:  now,
+		Referrer:   r.Referer(),
+		UAFamily:   family,
+		Device:     device,
+		IPHash:     app.ipHasher.Hash(realip.FromRequest(r), now),
+		Counted:    counted,
+	}
+
+	err := app.clicks.Record(click)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *App) showShorteningStatsHandler(w http.ResponseWriter, r *http.Request) {
+	identifier := app.readIdentifierParam(r)
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	interval := app.readString(qs, "interval", "day")
+	to := app.readTime(qs, "to", time.Now(), v)
+	from := app.readTime(qs, "from", to.AddDate(0, 0, -7), v)
+
+	if model.ValidateStatsRange(v, interval, from, to); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	domain := app.readDomainParam(r)
+
+	shortening, err := app.Storage.Shortenings.Get(domain, identifier)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.requireShorteningOwner(w, r, shortening) {
+		return
+	}
+
+	stats, err := app.Storage.Clicks.Stats(domain, identifier, interval, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
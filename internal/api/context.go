@@ -15,6 +15,12 @@ type contextKey string
 // in the request context.
 const userContextKey = contextKey("user")
 
+// permissionsContextKey holds the permissions of the user, loaded once by authenticate.
+const permissionsContextKey = contextKey("permissions")
+
+// tokenContextKey holds the session token or API key the request was authenticated with.
+const tokenContextKey = contextKey("token")
+
 func (app *App) contextSetUser(r *http.Request, user *model.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
 	return r.WithContext(ctx)
@@ -27,3 +33,27 @@ func (app *App) contextGetUser(r *http.Request) *model.User {
 	}
 	return user
 }
+
+func (app *App) contextSetPermissions(r *http.Request, permissions model.Permissions) *http.Request {
+	ctx := context.WithValue(r.Context(), permissionsContextKey, permissions)
+	return r.WithContext(ctx)
+}
+
+func (app *App) contextGetPermissions(r *http.Request) model.Permissions {
+	permissions, ok := r.Context().Value(permissionsContextKey).(model.Permissions)
+	if !ok {
+		panic("missing permissions value in request context")
+	}
+	return permissions
+}
+
+func (app *App) contextSetToken(r *http.Request, token *model.Token) *http.Request {
+	ctx := context.WithValue(r.Context(), tokenContextKey, token)
+	return r.WithContext(ctx)
+}
+
+// contextGetToken returns nil for anonymous requests.
+func (app *App) contextGetToken(r *http.Request) *model.Token {
+	token, _ := r.Context().Value(tokenContextKey).(*model.Token)
+	return token
+}
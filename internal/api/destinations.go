@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// checkDestinations launches a background goroutine which health checks the destinations that
+// are due once every configured poll interval.
+func (app *App) checkDestinations() {
+	if !app.Config.LinkCheck.Enabled {
+		return
+	}
+
+	app.background(func() {
+		ticker := time.NewTicker(app.Config.LinkCheck.PollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			checked := 0
+
+			// Keep going while full batches come back, there may be more due destinations.
+			for {
+				n, err := app.destinations.RunOnce(context.Background())
+				if err != nil {
+					app.Logger.PrintError(err, nil)
+					break
+				}
+
+				checked += n
+				if n < app.Config.LinkCheck.BatchSize {
+					break
+				}
+			}
+
+			if checked > 0 {
+				app.Logger.PrintInfo("checked destinations", map[string]string{
+					"count": strconv.Itoa(checked),
+				})
+			}
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+	"github.com/yantay0/url-shortener/internal/validator"
+)
+
+// createDomainHandler registers a custom domain for the current user. The response tells the
+// user which TXT record to publish before the domain can be verified.
+func (app *App) createDomainHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Host string `json:"host"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	domain, err := model.NewDomain(user.ID, input.Host)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if model.ValidateDomain(v, domain); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.Storage.Domains.Insert(domain)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrDuplicateDomain):
+			v.AddError("host", "is already registered by this user or verified by another one")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, app.domainEnvelope(domain), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *App) listDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	domains, err := app.Storage.Domains.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"domains": domains}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// verifyDomainHandler looks up the TXT record of a domain of the current user and marks the
+// domain as verified if it holds the verification token.
+func (app *App) verifyDomainHandler(w http.ResponseWriter, r *http.Request) {
+	domain, ok := app.ownDomain(w, r)
+	if !ok {
+		return
+	}
+
+	if !domain.IsVerified() {
+		ctx, cancel := context.WithTimeout(r.Context(), app.Config.Domains.VerifyTimeout)
+		defer cancel()
+
+		// Lookup failures are mostly misconfigured name servers of the domain, which the user has
+		// to fix, so they are reported like a missing record.
+		verified, err := app.Verifier.Verify(ctx, domain.Host, domain.VerificationToken)
+		if err != nil {
+			app.logError(r, err)
+		}
+
+		if !verified {
+			v := validator.New()
+			v.AddError("host", fmt.Sprintf("no TXT record at %s holds the verification token", app.Verifier.RecordName(domain.Host)))
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = app.Storage.Domains.MarkVerified(domain)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrDuplicateDomain):
+				v := validator.New()
+				v.AddError("host", "is already verified by another user")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	err := app.writeJSON(w, http.StatusOK, app.domainEnvelope(domain), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteDomainHandler deletes a domain of the current user, a pending claim as well as a verified
+// domain together with its shortenings.
+func (app *App) deleteDomainHandler(w http.ResponseWriter, r *http.Request) {
+	domain, ok := app.ownDomain(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.Storage.Domains.Delete(domain)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "domain successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// ownDomain returns the domain from the :id path parameter if it belongs to the current user.
+// Otherwise it sends a response and returns false, other users' domains are reported as missing
+// rather than forbidden.
+func (app *App) ownDomain(w http.ResponseWriter, r *http.Request) (*model.Domain, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	domain, err := app.Storage.Domains.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if domain.UserID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return domain, true
+}
+
+// domainEnvelope wraps a domain together with the TXT record that proves control over it.
+func (app *App) domainEnvelope(domain *model.Domain) envelope {
+	return envelope{
+		"domain": domain,
+		"verification": map[string]string{
+			"type":  "TXT",
+			"name":  app.Verifier.RecordName(domain.Host),
+			"value": domain.VerificationToken,
+		},
+	}
+}
+
+// userDomain returns the host of a custom domain a user creates a shortening on. The domain has
+// to belong to the user and be verified, errors for the client are added to v.
+func (app *App) userDomain(v *validator.Validator, userID int64, host string) (string, error) {
+	// Only normalizes the host, an invalid one can't be registered and isn't found below.
+	domain := &model.Domain{Host: host}
+	model.ValidateDomain(validator.New(), domain)
+
+	found, err := app.Storage.Domains.GetByHost(domain.Host)
+	switch {
+	case errors.Is(err, storage.ErrRecordNotFound):
+	case err != nil:
+		return "", err
+	case found.UserID == userID:
+		return found.Host, nil
+	}
+
+	v.AddError("domain", "must be a verified domain of the user")
+	return "", nil
+}
+
+// domainForHost returns the custom domain a request was made on, or the empty default domain if
+// the host isn't a verified custom domain. The lookup is cached, see storage.CachedDomainsStorage.
+func (app *App) domainForHost(hostport string) (string, error) {
+	domain, err := app.Storage.Domains.GetByHost(model.HostName(hostport))
+	switch {
+	case errors.Is(err, storage.ErrRecordNotFound):
+		return "", nil
+	case err != nil:
+		return "", err
+	default:
+		return domain.Host, nil
+	}
+}
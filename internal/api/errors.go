@@ -101,3 +101,21 @@ func (app *App) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (app *App) sessionTokenRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this resource requires a session token, API keys can't access it"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// linkExpiredResponse sends a JSON-formatted error with a 410 Gone status code to the client.
+func (app *App) linkExpiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the requested link has expired"
+	app.errorResponse(w, r, http.StatusGone, message)
+}
+
+// linkPasswordRequiredResponse sends a JSON-formatted error with a 401 Unauthorized status code
+// to the client when a protected link was requested without the correct password.
+func (app *App) linkPasswordRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this link is password protected, provide the correct password in the X-Link-Password header"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
@@ -9,8 +9,11 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/pages"
 	"github.com/yantay0/url-shortener/internal/validator"
 )
 
@@ -31,6 +34,28 @@ func (app *App) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// readIdentifierParam returns the :identifier parameter of the current route,
+// or an empty string if the route doesn't have one.
+func (app *App) readIdentifierParam(r *http.Request) string {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	return params.ByName("identifier")
+}
+
+// readStringParam returns the named parameter of the current route, or an empty string if the
+// route doesn't have it.
+func (app *App) readStringParam(r *http.Request, name string) string {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	return params.ByName(name)
+}
+
+// readDomainParam returns the custom domain of the shortening addressed by the current request
+// from the "domain" query parameter, or the empty default domain.
+func (app *App) readDomainParam(r *http.Request) string {
+	return model.HostName(r.URL.Query().Get("domain"))
+}
+
 func (app *App) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
 	js, err := json.MarshalIndent(data, "", "\t")
 	if err != nil {
@@ -49,6 +74,20 @@ func (app *App) writeJSON(w http.ResponseWriter, status int, data envelope, head
 	return nil
 }
 
+// writeHTML renders one of the embedded page templates and sends it to the client.
+func (app *App) writeHTML(w http.ResponseWriter, status int, templateFile string, data interface{}) error {
+	body, err := pages.Render(templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+
+	return nil
+}
+
 func (app *App) readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBodyBytes))
 
@@ -89,6 +128,31 @@ func (app *App) readJSON(w http.ResponseWriter, r *http.Request, dst interface{}
 	return nil
 }
 
+// optional is a field of a JSON request body that tells a missing key apart from null. Set
+// reports whether the key was present, Value is nil if it was null.
+type optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (o *optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var value T
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	o.Value = &value
+
+	return nil
+}
+
 // returns string value\defalut from query string
 func (app *App) readString(qs url.Values, key string, defaultValue string) string {
 	s := qs.Get(key)
@@ -117,6 +181,40 @@ func (app *App) readInt(qs url.Values, key string, defaultValue int, v *validato
 	return i
 }
 
+// readTime returns an RFC 3339 timestamp from the query string or the default value.
+func (app *App) readTime(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp")
+		return defaultValue
+	}
+
+	return t
+}
+
+// readBool returns an optional boolean from the query string, nil if the key is missing.
+func (app *App) readBool(qs url.Values, key string, v *validator.Validator) *bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be true or false")
+		return nil
+	}
+
+	return &b
+}
+
 // The background() helper accepts an arbitrary function as a parameter.
 func (app *App) background(fn func()) {
 	go func() {
@@ -129,3 +227,13 @@ func (app *App) background(fn func()) {
 		fn()
 	}()
 }
+
+// aliasRules returns the rules custom aliases are validated against. The configured reserved
+// words are extended with the top-level route segments.
+func (app *App) aliasRules() validator.AliasRules {
+	return validator.AliasRules{
+		MinLength: app.Config.Alias.MinLength,
+		MaxLength: app.Config.Alias.MaxLength,
+		Reserved:  append(append([]string{}, routeSegments...), app.Config.Alias.Reserved...),
+	}
+}
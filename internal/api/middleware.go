@@ -20,29 +20,47 @@ func (app *App) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		w.Header().Add("Vary", "Authorization")
+		w.Header().Add("Vary", "X-API-Key")
 
 		authorizationHeader := r.Header.Get("Authorization")
+		apiKeyHeader := r.Header.Get("X-API-Key")
 
-		if authorizationHeader == "" {
+		if authorizationHeader == "" && apiKeyHeader == "" {
 			r = app.contextSetUser(r, model.AnonymousUser)
+			r = app.contextSetPermissions(r, model.Permissions{})
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Split the authorization header into two parts. The first part should be
-		headerParts := strings.Split(authorizationHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			app.invalidAuthenticationTokenResponse(w, r)
-			return
+		// Session tokens are sent as "Bearer <token>", API keys as "ApiKey <key>" or in the
+		// X-API-Key header.
+		scope, plaintext := storage.ScopeAPIKey, apiKeyHeader
+		if authorizationHeader != "" {
+			headerParts := strings.Split(authorizationHeader, " ")
+			if len(headerParts) != 2 || apiKeyHeader != "" {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+
+			switch headerParts[0] {
+			case "Bearer":
+				scope = storage.ScopeAuthentication
+			case "ApiKey":
+				scope = storage.ScopeAPIKey
+			default:
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+			plaintext = headerParts[1]
 		}
-		token := headerParts[1]
+
 		v := validator.New()
-		if model.ValidateTokenPlaintext(v, token); !v.Valid() {
+		if model.ValidateTokenPlaintext(v, plaintext); !v.Valid() {
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 
-		user, err := app.Storage.Users.GetForToken(storage.ScopeAuthentication, token)
+		token, err := app.Storage.Tokens.GetForPlaintext(scope, plaintext)
 		if err != nil {
 			switch {
 			case errors.Is(err, storage.ErrRecordNotFound):
@@ -52,13 +70,48 @@ func (app *App) authenticate(next http.Handler) http.Handler {
 			}
 			return
 		}
+
+		user, err := app.Storage.Users.GetByID(token.UserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrRecordNotFound):
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		// Load the permissions once per request, the handlers and requirePermission() read them
+		// from the context.
+		permissions, err := app.Storage.Permissions.GetAllForUser(user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		// API keys can only use the part of the user's permissions they were created with.
+		if token.Scope == storage.ScopeAPIKey {
+			permissions = permissions.Restrict(token.Permissions)
+		}
+
+		// The last use is only recorded once a minute rather than on every request.
+		if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > time.Minute {
+			err = app.Storage.Tokens.MarkUsed(token)
+			if err != nil {
+				app.logError(r, err)
+			}
+		}
+
 		// Call the contextSetUser() helper to add the user information to the request
 		// context.
 		r = app.contextSetUser(r, user)
+		r = app.contextSetPermissions(r, permissions)
+		r = app.contextSetToken(r, token)
 		// Call the next handler in the chain.
 		next.ServeHTTP(w, r)
 	})
 }
+
 func (app *App) rateLimit(next http.Handler) http.Handler {
 	// Define a client struct to hold the rate limiter and last seen time for reach client
 	type client struct {
@@ -159,16 +212,25 @@ func (app *App) requireActivatedUser(next http.HandlerFunc) http.HandlerFunc {
 	// Wrap fn with the requireAuthenticatedUser() middleware before returning it.
 	return app.requireAuthenticatedUser(fn)
 }
-func (app *App) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
-	fn := func(w http.ResponseWriter, r *http.Request) {
-		// Retrieve the user from the request context.
-		user := app.contextGetUser(r)
-		// Get the slice of permissions for the user.
-		permissions, err := app.Storage.Permissions.GetAllForUser(user.ID)
-		if err != nil {
-			app.serverErrorResponse(w, r, err)
+
+// requireSessionToken only lets requests authenticated with a session token through. API keys
+// mustn't manage the account they belong to, e.g. create unrestricted keys. It has to be wrapped
+// by requireAuthenticatedUser().
+func (app *App) requireSessionToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetToken(r).Scope != storage.ScopeAuthentication {
+			app.sessionTokenRequiredResponse(w, r)
 			return
 		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func (app *App) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		// Get the slice of permissions granted to the user directly or through their roles,
+		// loaded by authenticate().
+		permissions := app.contextGetPermissions(r)
 		// Check if the slice includes the required permission. If it doesn't, then
 		// return a 403 Forbidden response.
 		if !permissions.Include(code) {
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+	"github.com/yantay0/url-shortener/internal/validator"
+)
+
+func (app *App) listPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	permissions, err := app.Storage.Permissions.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"permissions": permissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createPermissionHandler adds a new permission code. It's granted to the admin role right away,
+// other roles and users get it through the grant endpoints.
+func (app *App) createPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if model.ValidatePermissionCode(v, input.Code); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.Storage.Permissions.Insert(input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrDuplicatePermission):
+			v.AddError("code", "already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"permission": input.Code}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *App) showUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUser(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeUserPermissionsResponse(w, r, user.ID)
+}
+
+func (app *App) grantUserPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// AddForUser ignores unknown codes, so they have to be reported here.
+	permissions, err := app.Storage.Permissions.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !permissions.Include(input.Code) {
+		v := validator.New()
+		v.AddError("code", "must be an existing permission")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.Storage.Permissions.AddForUser(user.ID, input.Code)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeUserPermissionsResponse(w, r, user.ID)
+}
+
+// revokeUserPermissionHandler removes a direct grant. If one of the user's roles includes the
+// permission too, the user keeps it and a 409 Conflict response says so, the role has to be
+// revoked instead.
+func (app *App) revokeUserPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUser(w, r)
+	if !ok {
+		return
+	}
+
+	code := app.readStringParam(r, "code")
+
+	err := app.Storage.Permissions.RemoveForUser(user.ID, code)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	permissions, err := app.Storage.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if permissions.Include(code) {
+		message := "the permission is still granted through a role of the user, revoke the role instead"
+		app.errorResponse(w, r, http.StatusConflict, message)
+		return
+	}
+
+	app.writeUserPermissionsResponse(w, r, user.ID)
+}
+
+func (app *App) grantUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role string `json:"role"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.Storage.Permissions.AddRoleForUser(user.ID, input.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			v := validator.New()
+			v.AddError("role", "must be an existing role")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeUserPermissionsResponse(w, r, user.ID)
+}
+
+func (app *App) revokeUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.Storage.Permissions.RemoveRoleForUser(user.ID, app.readStringParam(r, "role"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			v := validator.New()
+			v.AddError("role", "must be an existing role")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, storage.ErrRoleNotGranted):
+			app.errorResponse(w, r, http.StatusConflict, "the user doesn't have the role")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeUserPermissionsResponse(w, r, user.ID)
+}
+
+// readUser returns the user from the :id parameter of the current route. It sends a 404 Not Found
+// response and returns false if there is no such user.
+func (app *App) readUser(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	user, err := app.Storage.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
+
+// writeUserPermissionsResponse sends the roles of a user together with the effective permissions
+// granted directly and through the roles.
+func (app *App) writeUserPermissionsResponse(w http.ResponseWriter, r *http.Request, userID int64) {
+	roles, err := app.Storage.Permissions.GetRolesForUser(userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	permissions, err := app.Storage.Permissions.GetAllForUser(userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"roles": roles, "permissions": permissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+func TestRevokeUserRole(t *testing.T) {
+	app := newTestApp(t)
+	_, token := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+	user, _ := newTestUser(t, app, "editor@example.com", model.RoleEditor)
+	path := fmt.Sprintf("/api/v1/users/%d/roles/", user.ID)
+
+	w := request(t, app, http.MethodDelete, path+"unknown", bearer(token), "")
+	checkStatus(t, w, http.StatusUnprocessableEntity)
+
+	w = request(t, app, http.MethodDelete, path+model.RoleViewer, bearer(token), "")
+	checkStatus(t, w, http.StatusConflict)
+
+	w = request(t, app, http.MethodDelete, path+model.RoleEditor, bearer(token), "")
+	checkStatus(t, w, http.StatusOK)
+
+	// The role is gone now.
+	w = request(t, app, http.MethodDelete, path+model.RoleEditor, bearer(token), "")
+	checkStatus(t, w, http.StatusConflict)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+// adminPermission lets a user act on the shortenings of every user, other users are limited to
+// their own.
+const adminPermission = "shortenings:admin"
+
+// isAdmin reports whether the current user has the admin override permission.
+func (app *App) isAdmin(r *http.Request) bool {
+	return app.contextGetPermissions(r).Include(adminPermission)
+}
+
+// canAccess reports whether the current user may act on resources owned by the user with the
+// given ID.
+func (app *App) canAccess(r *http.Request, ownerID int64) bool {
+	user := app.contextGetUser(r)
+	if !user.IsAnonymous() && user.ID == ownerID {
+		return true
+	}
+
+	return app.isAdmin(r)
+}
+
+// requireOwner sends a 403 Forbidden response and returns false if the current user may not act
+// on resources of the user with the given ID.
+func (app *App) requireOwner(w http.ResponseWriter, r *http.Request, ownerID int64) bool {
+	if !app.canAccess(r, ownerID) {
+		app.notPermittedResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+// requireShorteningOwner checks that the current user owns the shortening or is an admin.
+func (app *App) requireShorteningOwner(w http.ResponseWriter, r *http.Request, shortening *model.Shortening) bool {
+	return app.requireOwner(w, r, shortening.UserID)
+}
+
+// requireUserOwner only lets the user from the :id path parameter, or an admin, through to the
+// user-scoped routes.
+func (app *App) requireUserOwner(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := app.readIDParam(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if !app.requireOwner(w, r, userID) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
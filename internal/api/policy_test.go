@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+// policyFixture holds an app with a shortening of owner and a user of each other kind.
+type policyFixture struct {
+	app    *App
+	owner  *model.User
+	tokens map[string]string // authentication tokens by kind of user
+}
+
+func newPolicyFixture(t *testing.T) *policyFixture {
+	t.Helper()
+
+	app := newTestApp(t)
+
+	owner, ownerToken := newTestUser(t, app, "owner@example.com", model.RoleEditor)
+	_, otherToken := newTestUser(t, app, "other@example.com", model.RoleEditor)
+	_, viewerToken := newTestUser(t, app, "viewer@example.com", model.RoleViewer)
+	_, adminToken := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+
+	err := app.Storage.Shortenings.SaveUserShortening(&model.Shortening{
+		Identifier:  "abcdefg",
+		OriginalURL: "https://example.com/",
+		UserID:      owner.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &policyFixture{
+		app:   app,
+		owner: owner,
+		tokens: map[string]string{
+			"owner":  ownerToken,
+			"other":  otherToken,
+			"viewer": viewerToken,
+			"admin":  adminToken,
+		},
+	}
+}
+
+func TestShorteningRoutesByRole(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   map[string]int // status by kind of user
+	}{
+		{
+			method: http.MethodGet,
+			path:   "/api/v1/shortenings/abcdefg",
+			want:   map[string]int{"owner": 200, "other": 403, "viewer": 403, "admin": 200},
+		},
+		{
+			method: http.MethodPatch,
+			path:   "/api/v1/shortenings/abcdefg",
+			body:   `{"original_url": "https://example.org/"}`,
+			want:   map[string]int{"owner": 200, "other": 403, "viewer": 403, "admin": 200},
+		},
+		{
+			method: http.MethodDelete,
+			path:   "/api/v1/shortenings/abcdefg",
+			want:   map[string]int{"owner": 200, "other": 403, "viewer": 403, "admin": 200},
+		},
+		{
+			method: http.MethodGet,
+			path:   "/api/v1/shortenings/abcdefg/stats",
+			want:   map[string]int{"owner": 200, "other": 403, "viewer": 403, "admin": 200},
+		},
+		{
+			method: http.MethodGet,
+			path:   "/api/v1/shortenings/abcdefg/qr",
+			want:   map[string]int{"owner": 200, "other": 403, "viewer": 403, "admin": 200},
+		},
+		{
+			method: http.MethodGet,
+			path:   "/api/v1/users/%d/shortenings",
+			want:   map[string]int{"owner": 200, "other": 403, "viewer": 403, "admin": 200},
+		},
+		{
+			method: http.MethodPost,
+			path:   "/api/v1/users/%d/shortenings",
+			body:   `{"original_url": "https://example.net/"}`,
+			want:   map[string]int{"owner": 201, "other": 403, "viewer": 403, "admin": 201},
+		},
+	}
+
+	for _, tt := range tests {
+		for kind, want := range tt.want {
+			t.Run(fmt.Sprintf("%s %s as %s", tt.method, tt.path, kind), func(t *testing.T) {
+				f := newPolicyFixture(t)
+
+				path := tt.path
+				if tt.path == "/api/v1/users/%d/shortenings" {
+					path = fmt.Sprintf(tt.path, f.owner.ID)
+				}
+
+				w := request(t, f.app, tt.method, path, bearer(f.tokens[kind]), tt.body)
+				checkStatus(t, w, want)
+			})
+		}
+	}
+}
+
+func TestListShorteningsByRole(t *testing.T) {
+	f := newPolicyFixture(t)
+
+	// Everybody may list, but only admins see the shortenings of other users.
+	want := map[string]int{"owner": 1, "other": 0, "viewer": 0, "admin": 1}
+
+	for kind, count := range want {
+		w := request(t, f.app, http.MethodGet, "/api/v1/shortenings", bearer(f.tokens[kind]), "")
+		checkStatus(t, w, http.StatusOK)
+
+		var body struct {
+			Shortenings []json.RawMessage `json:"shorternings"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Shortenings) != count {
+			t.Errorf("%s listed %d shortenings, want %d", kind, len(body.Shortenings), count)
+		}
+	}
+}
+
+func TestCreateShorteningNeedsWritePermission(t *testing.T) {
+	app := newTestApp(t)
+
+	viewer, viewerToken := newTestUser(t, app, "viewer@example.com", model.RoleViewer)
+	editor, _ := newTestUser(t, app, "editor@example.com", model.RoleEditor)
+	readOnlyKey := newTestAPIKey(t, app, editor, "shortenings:read")
+	writeKey := newTestAPIKey(t, app, editor, "shortenings:write")
+
+	body := `{"original_url": "https://example.com/"}`
+
+	w := request(t, app, http.MethodPost, fmt.Sprintf("/api/v1/users/%d/shortenings", viewer.ID), bearer(viewerToken), body)
+	checkStatus(t, w, http.StatusForbidden)
+
+	path := fmt.Sprintf("/api/v1/users/%d/shortenings", editor.ID)
+
+	w = request(t, app, http.MethodPost, path, "ApiKey "+readOnlyKey, body)
+	checkStatus(t, w, http.StatusForbidden)
+
+	w = request(t, app, http.MethodPost, path, "ApiKey "+writeKey, body)
+	checkStatus(t, w, http.StatusCreated)
+}
+
+func TestRevokePermissionGrantedByRole(t *testing.T) {
+	app := newTestApp(t)
+
+	_, adminToken := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+	editor, _ := newTestUser(t, app, "editor@example.com", model.RoleEditor)
+
+	err := app.Storage.Permissions.AddForUser(editor.ID, "shortenings:write", "permissions:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The editor role still grants shortenings:write.
+	w := request(t, app, http.MethodDelete, fmt.Sprintf("/api/v1/users/%d/permissions/shortenings:write", editor.ID), bearer(adminToken), "")
+	checkStatus(t, w, http.StatusConflict)
+
+	w = request(t, app, http.MethodDelete, fmt.Sprintf("/api/v1/users/%d/permissions/permissions:read", editor.ID), bearer(adminToken), "")
+	checkStatus(t, w, http.StatusOK)
+
+	permissions, err := app.Storage.Permissions.GetAllForUser(editor.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if permissions.Include("permissions:read") {
+		t.Error("revoked permission is still granted")
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+// fetchPreview fetches the metadata of the destination page in the background and stores it for
+// the preview page. Pages that can't be fetched get a preview without metadata, which also
+// clears the metadata of a previous destination.
+func (app *App) fetchPreview(shortening *model.Shortening) {
+	if !app.Config.Preview.Enabled {
+		return
+	}
+
+	domain, identifier, originalURL := shortening.Domain, shortening.Identifier, shortening.OriginalURL
+
+	app.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), app.Config.Preview.Timeout)
+		defer cancel()
+
+		md, err := app.previews.Fetch(ctx, originalURL)
+		if err != nil {
+			app.Logger.PrintInfo("could not fetch preview metadata", map[string]string{
+				"identifier": identifier,
+				"error":      err.Error(),
+			})
+		}
+
+		err = app.Storage.Shortenings.RecordPreview(&model.Shortening{
+			Identifier:         identifier,
+			Domain:             domain,
+			PreviewTitle:       md.Title,
+			PreviewDescription: md.Description,
+			PreviewImage:       md.Image,
+		})
+		if err != nil {
+			app.Logger.PrintError(err, map[string]string{"identifier": identifier})
+		}
+	})
+}
+
+// previewHandler renders the preview page of a short link, reached by appending a '+' to the
+// short link. The page links back to the short link rather than to the destination, so the
+// password, safety checks and click counting still apply when the visitor continues.
+func (app *App) previewHandler(w http.ResponseWriter, r *http.Request, domain, identifier string) {
+	shortening, err := app.Storage.Shortenings.Get(domain, identifier)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if shortening.Expired(time.Now()) {
+		app.linkExpiredResponse(w, r)
+		return
+	}
+
+	owner := ""
+	user, err := app.Storage.Users.GetByID(shortening.UserID)
+	switch {
+	case err == nil:
+		owner = user.Name
+	case !errors.Is(err, storage.ErrRecordNotFound):
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	shortURL, err := app.shortURL(shortening)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"shortURL":  shortURL,
+		"owner":     owner,
+		"createdAt": shortening.CreatedAt.Format("January 2, 2006"),
+		"protected": shortening.IsProtected(),
+		"flagged":   shortening.SafetyVerdict == model.VerdictFlagged,
+	}
+
+	// Protected links don't give their destination away before they are unlocked.
+	if !shortening.IsProtected() {
+		data["originalURL"] = shortening.OriginalURL
+		data["title"] = shortening.PreviewTitle
+		data["description"] = shortening.PreviewDescription
+		data["image"] = shortening.PreviewImage
+	}
+
+	err = app.writeHTML(w, http.StatusOK, "preview.tmpl", data)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yantay0/url-shortener/internal/qr"
+	"github.com/yantay0/url-shortener/internal/storage"
+	"github.com/yantay0/url-shortener/internal/validator"
+)
+
+// showShorteningQRHandler returns a QR code of the full short URL as PNG or SVG. The format is
+// taken from the "format" query parameter, or from the Accept header if it's missing.
+func (app *App) showShorteningQRHandler(w http.ResponseWriter, r *http.Request) {
+	identifier := app.readIdentifierParam(r)
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	defaultFormat := "png"
+	if strings.Contains(r.Header.Get("Accept"), "image/svg+xml") {
+		defaultFormat = "svg"
+	}
+
+	format := app.readString(qs, "format", defaultFormat)
+	opts := qr.Options{
+		Size:   app.readInt(qs, "size", 256, v),
+		Level:  strings.ToUpper(app.readString(qs, "level", "M")),
+		Margin: app.readInt(qs, "margin", 4, v),
+	}
+	fg := app.readString(qs, "fg", "000000")
+	bg := app.readString(qs, "bg", "ffffff")
+
+	var err error
+	opts.Foreground, err = qr.ParseColor(fg)
+	if err != nil {
+		v.AddError("fg", err.Error())
+	}
+	opts.Background, err = qr.ParseColor(bg)
+	if err != nil {
+		v.AddError("bg", err.Error())
+	}
+
+	v.Check(validator.In(format, "png", "svg"), "format", "must be png or svg")
+	v.Check(opts.Size >= 64 && opts.Size <= 2048, "size", "must be between 64 and 2048")
+	v.Check(validator.In(opts.Level, "L", "M", "Q", "H"), "level", "must be one of L, M, Q or H")
+	v.Check(opts.Margin >= 0 && opts.Margin <= 16, "margin", "must be between 0 and 16")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	shortening, err := app.Storage.Shortenings.Get(app.readDomainParam(r), identifier)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.requireShorteningOwner(w, r, shortening) {
+		return
+	}
+
+	shortURL, err := app.shortURL(shortening)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The image only depends on the short URL and the options, so they make a stable ETag.
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s|%d|%v|%v", shortURL, format, opts.Size, opts.Level,
+		opts.Margin, opts.Foreground, opts.Background)))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	// Only the owner may fetch the image, so shared caches must not keep it.
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+
+	if match := r.Header.Get("If-None-Match"); match == "*" || strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var body []byte
+	switch format {
+	case "svg":
+		body, err = qr.SVG(shortURL, opts)
+		w.Header().Set("Content-Type", "image/svg+xml")
+	default:
+		body, err = qr.PNG(shortURL, opts)
+		w.Header().Set("Content-Type", "image/png")
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Write(body)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"expvar"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -8,6 +9,10 @@ import (
 
 const BASE_URL = "/api/v1"
 
+// routeSegments holds the top-level path segments served by Routes(). They are always reserved,
+// a short link with one of them as identifier could never be reached.
+var routeSegments = []string{"api", "debug"}
+
 func (app *App) Routes() http.Handler {
 	router := httprouter.New()
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
@@ -20,15 +25,51 @@ func (app *App) Routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, BASE_URL+"/shortenings/:identifier", app.requirePermission("shortenings:read", app.ShowShorterningHandler))
 	router.HandlerFunc(http.MethodPatch, BASE_URL+"/shortenings/:identifier", app.requirePermission("shortenings:write", app.UpdateShorterningHandler))
 	router.HandlerFunc(http.MethodDelete, BASE_URL+"/shortenings/:identifier", app.requirePermission("shortenings:write", app.DeleteShorterningHandler))
+	router.HandlerFunc(http.MethodGet, BASE_URL+"/shortenings/:identifier/stats", app.requirePermission("shortenings:read", app.showShorteningStatsHandler))
+	router.HandlerFunc(http.MethodGet, BASE_URL+"/shortenings/:identifier/qr", app.requirePermission("shortenings:read", app.showShorteningQRHandler))
 
 	router.HandlerFunc(http.MethodPost, BASE_URL+"/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, BASE_URL+"/users/activated", app.activateUserHandler)
-	router.HandlerFunc(http.MethodGet, BASE_URL+"/users/:id/shortenings", app.requirePermission("shortenings:read", app.listUserShorteningsHandler))
-	router.HandlerFunc(http.MethodPost, BASE_URL+"/users/:id/shortenings", app.createShorteningFromURLHandler)
+	router.HandlerFunc(http.MethodPatch, BASE_URL+"/users/:id", app.requireActivatedUser(app.requireSessionToken(app.requireUserOwner(app.updateUserHandler))))
+	router.HandlerFunc(http.MethodGet, BASE_URL+"/users/:id/shortenings", app.requirePermission("shortenings:read", app.requireUserOwner(app.listUserShorteningsHandler)))
+	router.HandlerFunc(http.MethodPost, BASE_URL+"/users/:id/shortenings", app.requirePermission("shortenings:write", app.requireUserOwner(app.createShorteningFromURLHandler)))
+	router.HandlerFunc(http.MethodGet, BASE_URL+"/users/:id/permissions", app.requirePermission("permissions:read", app.showUserPermissionsHandler))
+	router.HandlerFunc(http.MethodPost, BASE_URL+"/users/:id/permissions", app.requirePermission("permissions:write", app.grantUserPermissionHandler))
+	router.HandlerFunc(http.MethodDelete, BASE_URL+"/users/:id/permissions/:code", app.requirePermission("permissions:write", app.revokeUserPermissionHandler))
+	router.HandlerFunc(http.MethodPost, BASE_URL+"/users/:id/roles", app.requirePermission("permissions:write", app.grantUserRoleHandler))
+	router.HandlerFunc(http.MethodDelete, BASE_URL+"/users/:id/roles/:role", app.requirePermission("permissions:write", app.revokeUserRoleHandler))
 	router.HandlerFunc(http.MethodPost, BASE_URL+"/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodDelete, BASE_URL+"/tokens/authentication", app.requireAuthenticatedUser(app.requireSessionToken(app.deleteCurrentAuthenticationTokenHandler)))
+	router.HandlerFunc(http.MethodDelete, BASE_URL+"/tokens/authentication/:id", app.requireAuthenticatedUser(app.requireSessionToken(app.deleteAuthenticationTokenHandler)))
+	router.HandlerFunc(http.MethodGet, BASE_URL+"/tokens", app.requireAuthenticatedUser(app.requireSessionToken(app.listAuthenticationTokensHandler)))
+
+	router.HandlerFunc(http.MethodGet, BASE_URL+"/api-keys", app.requireActivatedUser(app.requireSessionToken(app.listAPIKeysHandler)))
+	router.HandlerFunc(http.MethodPost, BASE_URL+"/api-keys", app.requireActivatedUser(app.requireSessionToken(app.createAPIKeyHandler)))
+	router.HandlerFunc(http.MethodDelete, BASE_URL+"/api-keys/:id", app.requireActivatedUser(app.requireSessionToken(app.deleteAPIKeyHandler)))
+
+	router.HandlerFunc(http.MethodGet, BASE_URL+"/permissions", app.requirePermission("permissions:read", app.listPermissionsHandler))
+	router.HandlerFunc(http.MethodPost, BASE_URL+"/permissions", app.requirePermission("permissions:write", app.createPermissionHandler))
+
+	router.HandlerFunc(http.MethodGet, BASE_URL+"/domains", app.requireActivatedUser(app.requireSessionToken(app.listDomainsHandler)))
+	router.HandlerFunc(http.MethodPost, BASE_URL+"/domains", app.requireActivatedUser(app.requireSessionToken(app.createDomainHandler)))
+	router.HandlerFunc(http.MethodPost, BASE_URL+"/domains/:id/verify", app.requireActivatedUser(app.requireSessionToken(app.verifyDomainHandler)))
+	router.HandlerFunc(http.MethodDelete, BASE_URL+"/domains/:id", app.requireActivatedUser(app.requireSessionToken(app.deleteDomainHandler)))
+
+	// httprouter doesn't allow a top-level wildcard next to the /api/v1 prefix, so the public
+	// short links live on their own router and the mux dispatches between the two.
+	redirects := httprouter.New()
+	redirects.NotFound = http.HandlerFunc(app.notFoundResponse)
+	redirects.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
+
+	redirects.HandlerFunc(http.MethodGet, "/:identifier", app.redirectHandler)
+	redirects.HandlerFunc(http.MethodPost, "/:identifier", app.redirectHandler) // unlock form of protected links
+
+	mux := http.NewServeMux()
+	mux.Handle(BASE_URL+"/", router)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/", redirects)
 
-	// Define the wildcard route after all specific routes
-	return app.recoverPanic(app.rateLimit(app.authenticate(router)))
+	return app.recoverPanic(app.rateLimit(app.authenticate(mux)))
 }
 
 // func (app *App) Routes() http.Handler {
@@ -51,7 +92,7 @@ func (app *App) Routes() http.Handler {
 // 	router.HandleFunc("/users/activated", app.activateUserHandler).Methods("PUT")
 // 	router.HandleFunc("/tokens/authentication", app.createAuthenticationTokenHandler).Methods("POST")
 
-// 	router.HandleFunc("/users/:id/shortenings", app.requirePermission("shortenings:read", app.listUserShorteningsHandler)).Methods("GET")
+// 	router.HandleFunc("/users/:id/shortenings", app.requirePermission("shortenings:read", app.requireUserOwner(app.listUserShorteningsHandler))).Methods("GET")
 // 	router.HandleFunc("/users/:id/shortenings", app.createShorteningFromURLHandler).Methods("POST")
 
 // 	router.HandleFunc("/{identifier}", app.requirePermission("shortenings:read", app.redirectHandler)).Methods("GET")
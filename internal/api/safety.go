@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+// checkURLSafety runs the safety checks on the original URL of the shortening and sets the
+// verdict on it. The checks fail open: if they error or time out the URL is let through with an
+// empty verdict, so an unavailable provider doesn't take link creation down.
+func (app *App) checkURLSafety(r *http.Request, shortening *model.Shortening) {
+	// Without any configured check the URL isn't known to be safe, the verdict stays empty.
+	if app.Safety == nil {
+		shortening.SafetyVerdict = ""
+		shortening.SafetyReason = ""
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), app.Config.Safety.Timeout)
+	defer cancel()
+
+	verdict, err := app.Safety.Check(ctx, shortening.OriginalURL)
+
+	switch {
+	case verdict.Flagged:
+		shortening.SafetyVerdict = model.VerdictFlagged
+		shortening.SafetyReason = verdict.Reason
+	case err != nil:
+		app.logError(r, err)
+		shortening.SafetyVerdict = ""
+		shortening.SafetyReason = ""
+	default:
+		shortening.SafetyVerdict = model.VerdictSafe
+		shortening.SafetyReason = ""
+	}
+}
+
+// recheckURLSafety checks the destination of a shortening again on redirect and records the
+// verdict if it changed since the last check.
+func (app *App) recheckURLSafety(r *http.Request, shortening *model.Shortening) {
+	previous, reason := shortening.SafetyVerdict, shortening.SafetyReason
+
+	app.checkURLSafety(r, shortening)
+
+	if shortening.SafetyVerdict == "" {
+		// The check failed, keep what we knew before.
+		shortening.SafetyVerdict, shortening.SafetyReason = previous, reason
+		return
+	}
+
+	if shortening.SafetyVerdict != previous || shortening.SafetyReason != reason {
+		err := app.Storage.Shortenings.RecordVerdict(shortening.Domain, shortening.Identifier, shortening.SafetyVerdict, shortening.SafetyReason)
+		if err != nil {
+			app.logError(r, err)
+		}
+	}
+}
+
+// unsafeLinkResponse renders the interstitial page shown instead of redirecting to a flagged
+// destination. The visitor can still continue at their own risk.
+func (app *App) unsafeLinkResponse(w http.ResponseWriter, r *http.Request, shortening *model.Shortening) {
+	data := map[string]interface{}{
+		"originalURL": shortening.OriginalURL,
+		"reason":      shortening.SafetyReason,
+	}
+
+	err := app.writeHTML(w, http.StatusOK, "warning.tmpl", data)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -34,9 +34,24 @@ func (app *App) Serve() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		shutdownError <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+
+		// No handler is running anymore, flush the clicks that are still buffered.
+		app.Logger.PrintInfo("flushing buffered clicks", map[string]string{
+			"addr": srv.Addr,
+		})
+
+		shutdownError <- app.clicks.Shutdown(ctx)
 	}()
 
+	app.clicks.Start()
+	app.sweepExpiredShortenings()
+	app.checkDestinations()
+
 	app.Logger.PrintInfo("starting server", map[string]string{
 		"addr": srv.Addr,
 		"env":  app.Config.Env,
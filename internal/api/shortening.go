@@ -2,8 +2,8 @@ package api
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/yantay0/url-shortener/internal/model"
 	"github.com/yantay0/url-shortener/internal/storage"
@@ -29,19 +29,47 @@ func (app *App) CreateShorterningHandler(w http.ResponseWriter, r *http.Request)
 		UserID:      input.UserID,
 	}
 
+	v := validator.New()
+	validator.ValidateAlias(v, input.Identifier, app.aliasRules())
+	if model.ValidateShortening(v, shorterning); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.checkURLSafety(r, shorterning)
+	if shorterning.SafetyVerdict == model.VerdictFlagged {
+		v.AddError("original_url", "is flagged as unsafe: "+shorterning.SafetyReason)
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	err = app.Storage.Shortenings.Insert(shorterning)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, storage.ErrIdentifierExists):
+			v.AddError("identifier", "already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
+
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("api/v1/shorternings/%s", shorterning.Identifier))
-	fmt.Fprintf(w, "%+v\n", input)
+	headers.Set("Location", app.shorteningLocation(shorterning))
+
+	app.fetchPreview(shorterning)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"shorterning": shorterning}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
 func (app *App) ListShorterningsHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		OriginalURL string
+		Healthy     *bool
 		model.Filters
 	}
 
@@ -49,6 +77,7 @@ func (app *App) ListShorterningsHandler(w http.ResponseWriter, r *http.Request)
 	qs := r.URL.Query()
 
 	input.OriginalURL = app.readString(qs, "original_url", "")
+	input.Healthy = app.readBool(qs, "healthy", v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
@@ -61,7 +90,13 @@ func (app *App) ListShorterningsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	shorternings, metadata, err := app.Storage.Shortenings.GetAll(input.OriginalURL, input.Filters)
+	// Admins list the shortenings of all users, everybody else only their own.
+	var userID int64
+	if !app.isAdmin(r) {
+		userID = app.contextGetUser(r).ID
+	}
+
+	shorternings, metadata, err := app.Storage.Shortenings.GetAll(input.OriginalURL, input.Healthy, userID, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -74,10 +109,9 @@ func (app *App) ListShorterningsHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (app *App) ShowShorterningHandler(w http.ResponseWriter, r *http.Request) {
-	qs := r.URL.Query()
-	identifier := app.readString(qs, "identifier", "")
+	identifier := app.readIdentifierParam(r)
 
-	shorterning, err := app.Storage.Shortenings.Get(identifier)
+	shorterning, err := app.Storage.Shortenings.Get(app.readDomainParam(r), identifier)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrRecordNotFound):
@@ -88,6 +122,10 @@ func (app *App) ShowShorterningHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !app.requireShorteningOwner(w, r, shorterning) {
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"shorterning": shorterning}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -95,13 +133,10 @@ func (app *App) ShowShorterningHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *App) UpdateShorterningHandler(w http.ResponseWriter, r *http.Request) {
-	qs := r.URL.Query()
-	identifier := app.readString(qs, "identifier", "")
-	shorterning, err := app.Storage.Shortenings.Get(identifier)
+	identifier := app.readIdentifierParam(r)
+	shorterning, err := app.Storage.Shortenings.Get(app.readDomainParam(r), identifier)
 	if err != nil {
 		switch {
-		case errors.Is(err, storage.ErrEditConflict):
-			app.editConflictResponse(w, r)
 		case errors.Is(err, storage.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
 		default:
@@ -109,8 +144,17 @@ func (app *App) UpdateShorterningHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
+
+	if !app.requireShorteningOwner(w, r, shorterning) {
+		return
+	}
+
 	var input struct {
-		OriginalURL *string `json:"original_url"`
+		OriginalURL  *string             `json:"original_url"`
+		RedirectType *int                `json:"redirect_type"`
+		ExpiresAt    optional[time.Time] `json:"expires_at"` // null removes the expiry
+		MaxVisits    optional[int64]     `json:"max_visits"` // null removes the limit
+		Password     *string             `json:"password"`   // An empty password removes the protection.
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -123,12 +167,78 @@ func (app *App) UpdateShorterningHandler(w http.ResponseWriter, r *http.Request)
 		shorterning.OriginalURL = *input.OriginalURL
 	}
 
+	if input.RedirectType != nil {
+		shorterning.RedirectType = *input.RedirectType
+	}
+
+	if input.ExpiresAt.Set {
+		shorterning.ExpiresAt = input.ExpiresAt.Value
+	}
+
+	if input.MaxVisits.Set {
+		shorterning.MaxVisits = input.MaxVisits.Value
+	}
+
+	v := validator.New()
+	model.ValidateShortening(v, shorterning)
+	model.ValidateExpiration(v, shorterning)
+	if input.Password != nil && *input.Password != "" {
+		model.ValidatePasswordPlaintext(v, *input.Password)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if input.OriginalURL != nil {
+		app.checkURLSafety(r, shorterning)
+		if shorterning.SafetyVerdict == model.VerdictFlagged {
+			v.AddError("original_url", "is flagged as unsafe: "+shorterning.SafetyReason)
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	// Links created in dedupe mode keep their hash in sync with the destination.
+	if input.OriginalURL != nil && shorterning.URLHash != nil {
+		urlHash, err := model.HashURL(shorterning.OriginalURL)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		shorterning.URLHash = &urlHash
+	}
+
+	if input.Password != nil {
+		if *input.Password == "" {
+			shorterning.Password.Hash = nil
+		} else {
+			err = shorterning.Password.Set(*input.Password)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
 	err = app.Storage.Shortenings.Update(shorterning)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, storage.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, storage.ErrDuplicateURL):
+			v.AddError("original_url", "is already shortened by another link of this user on the domain")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	if input.OriginalURL != nil {
+		app.fetchPreview(shorterning)
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"shorterning": shorterning}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -136,10 +246,25 @@ func (app *App) UpdateShorterningHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (app *App) DeleteShorterningHandler(w http.ResponseWriter, r *http.Request) {
-	qs := r.URL.Query()
-	Identifier := app.readString(qs, "identifier", "")
+	Identifier := app.readIdentifierParam(r)
+	domain := app.readDomainParam(r)
+
+	shorterning, err := app.Storage.Shortenings.Get(domain, Identifier)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.requireShorteningOwner(w, r, shorterning) {
+		return
+	}
 
-	err := app.Storage.Shortenings.Delete(Identifier)
+	err = app.Storage.Shortenings.Delete(domain, Identifier)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrRecordNotFound):
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomasen/realip"
+	"golang.org/x/time/rate"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+// attemptLimiter rate limits wrong password attempts per client and protected link. It uses the
+// same per-key token bucket approach as the rateLimit middleware, but a token is only spent when
+// an attempt fails.
+type attemptLimiter struct {
+	mu      sync.Mutex
+	rps     float64
+	burst   int
+	clients map[string]*attemptClient
+}
+
+type attemptClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newAttemptLimiter(rps float64, burst int) *attemptLimiter {
+	l := &attemptLimiter{
+		rps:     rps,
+		burst:   burst,
+		clients: make(map[string]*attemptClient),
+	}
+
+	// Remove the clients that haven't been seen within the last three minutes once every minute.
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+
+			l.mu.Lock()
+			for key, client := range l.clients {
+				if time.Since(client.lastSeen) > 3*time.Minute {
+					delete(l.clients, key)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}()
+
+	return l
+}
+
+func (l *attemptLimiter) client(key string) *attemptClient {
+	if _, found := l.clients[key]; !found {
+		l.clients[key] = &attemptClient{limiter: rate.NewLimiter(rate.Limit(l.rps), l.burst)}
+	}
+
+	l.clients[key].lastSeen = time.Now()
+
+	return l.clients[key]
+}
+
+// Available reports whether the client identified by key may make another attempt.
+func (l *attemptLimiter) Available(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.client(key).limiter.Tokens() >= 1
+}
+
+// Fail spends one token of the client identified by key.
+func (l *attemptLimiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.client(key).limiter.Allow()
+}
+
+// unlockShortening checks the password of a protected shortening. The password is read from the
+// X-Link-Password header or, for the HTML unlock form, from the "password" form field. It returns
+// true if the client may be redirected, otherwise a response has already been sent.
+func (app *App) unlockShortening(w http.ResponseWriter, r *http.Request, shortening *model.Shortening) bool {
+	html := r.Method == http.MethodPost || strings.Contains(r.Header.Get("Accept"), "text/html")
+
+	plaintext := r.Header.Get("X-Link-Password")
+	if r.Method == http.MethodPost {
+		plaintext = r.PostFormValue("password")
+	}
+
+	if plaintext == "" {
+		if html {
+			app.unlockFormResponse(w, r, shortening, http.StatusOK, "")
+		} else {
+			app.linkPasswordRequiredResponse(w, r)
+		}
+		return false
+	}
+
+	key := realip.FromRequest(r) + "/" + shortening.Domain + "/" + shortening.Identifier
+	if !app.unlockAttempts.Available(key) {
+		app.rateLimitExceededResponse(w, r)
+		return false
+	}
+
+	match, err := shortening.Password.Matches(plaintext)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if !match {
+		app.unlockAttempts.Fail(key)
+		if html {
+			app.unlockFormResponse(w, r, shortening, http.StatusUnauthorized, "wrong password, please try again")
+		} else {
+			app.linkPasswordRequiredResponse(w, r)
+		}
+		return false
+	}
+
+	return true
+}
+
+// unlockFormResponse renders the HTML form which asks for the password of a protected link.
+func (app *App) unlockFormResponse(w http.ResponseWriter, r *http.Request, shortening *model.Shortening, status int, message string) {
+	data := map[string]interface{}{
+		"identifier": shortening.Identifier,
+		"error":      message,
+	}
+
+	err := app.writeHTML(w, status, "unlock.tmpl", data)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
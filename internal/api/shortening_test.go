@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+func TestUpdateShorteningExpiration(t *testing.T) {
+	app := newTestApp(t)
+	user, token := newTestUser(t, app, "owner@example.com", model.RoleEditor)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	maxVisits := int64(10)
+	err := app.Storage.Shortenings.SaveUserShortening(&model.Shortening{
+		Identifier:  "abcdefg",
+		OriginalURL: "https://example.com/",
+		UserID:      user.ID,
+		ExpiresAt:   &expiresAt,
+		MaxVisits:   &maxVisits,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(body string) *model.Shortening {
+		t.Helper()
+
+		w := request(t, app, http.MethodPatch, "/api/v1/shortenings/abcdefg", bearer(token), body)
+		checkStatus(t, w, http.StatusOK)
+
+		var response struct {
+			Shortening *model.Shortening `json:"shorterning"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return response.Shortening
+	}
+
+	// Missing keys keep the current values.
+	shortening := update(`{"redirect_type": 301}`)
+	if shortening.ExpiresAt == nil || shortening.MaxVisits == nil {
+		t.Fatalf("got expires_at %v and max_visits %v, want both kept", shortening.ExpiresAt, shortening.MaxVisits)
+	}
+
+	shortening = update(`{"expires_at": null}`)
+	if shortening.ExpiresAt != nil || shortening.MaxVisits == nil {
+		t.Fatalf("got expires_at %v and max_visits %v, want only the expiry cleared", shortening.ExpiresAt, shortening.MaxVisits)
+	}
+
+	shortening = update(`{"max_visits": null}`)
+	if shortening.MaxVisits != nil {
+		t.Fatalf("got max_visits %v, want it cleared", *shortening.MaxVisits)
+	}
+
+	w := request(t, app, http.MethodPatch, "/api/v1/shortenings/abcdefg", bearer(token), `{"max_visits": 0}`)
+	checkStatus(t, w, http.StatusUnprocessableEntity)
+
+	w = request(t, app, http.MethodPatch, "/api/v1/shortenings/abcdefg", bearer(token), `{"max_visits": "ten"}`)
+	checkStatus(t, w, http.StatusBadRequest)
+}
+
+func TestUpdateExpiredShorteningNeedsNewExpiry(t *testing.T) {
+	app := newTestApp(t)
+	user, token := newTestUser(t, app, "owner@example.com", model.RoleEditor)
+
+	expiresAt := time.Now().Add(-time.Hour)
+	err := app.Storage.Shortenings.SaveUserShortening(&model.Shortening{
+		Identifier:  "abcdefg",
+		OriginalURL: "https://example.com/",
+		UserID:      user.ID,
+		ExpiresAt:   &expiresAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The merged shortening is validated, the expiry in the past isn't taken over silently.
+	w := request(t, app, http.MethodPatch, "/api/v1/shortenings/abcdefg", bearer(token), `{"redirect_type": 301}`)
+	checkStatus(t, w, http.StatusUnprocessableEntity)
+
+	w = request(t, app, http.MethodPatch, "/api/v1/shortenings/abcdefg", bearer(token), `{"redirect_type": 301, "expires_at": null}`)
+	checkStatus(t, w, http.StatusOK)
+}
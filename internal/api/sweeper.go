@@ -0,0 +1,33 @@
+package api
+
+import (
+	"strconv"
+	"time"
+)
+
+// sweepExpiredShortenings launches a background goroutine which archives expired shortenings
+// once every configured interval.
+func (app *App) sweepExpiredShortenings() {
+	if !app.Config.Sweeper.Enabled {
+		return
+	}
+
+	app.background(func() {
+		ticker := time.NewTicker(app.Config.Sweeper.Interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			archived, err := app.Storage.Shortenings.ArchiveExpired()
+			if err != nil {
+				app.Logger.PrintError(err, nil)
+				continue
+			}
+
+			if archived > 0 {
+				app.Logger.PrintInfo("archived expired shortenings", map[string]string{
+					"count": strconv.FormatInt(archived, 10),
+				})
+			}
+		}
+	})
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/yantay0/url-shortener/internal/model"
@@ -10,6 +11,9 @@ import (
 	"github.com/yantay0/url-shortener/internal/validator"
 )
 
+// maxUserAgentBytes limits the User-Agent stored with a session token.
+const maxUserAgentBytes = 512
+
 func (app *App) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Email    string `json:"email"`
@@ -29,7 +33,7 @@ func (app *App) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	user, err := app.Storage.Users.GetByEmail(input.Email)
+	user, err := app.Storage.Users.GetByEmail(model.NormalizeEmail(input.Email))
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrRecordNotFound):
@@ -53,7 +57,19 @@ func (app *App) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.
 
 	// Otherwise, if the password is correct, we generate a new token with a 24-hour
 	// expiry time and the scope 'authentication'.
-	token, err := app.Storage.Tokens.New(user.ID, 24*time.Hour, storage.ScopeAuthentication)
+	token, err := model.GenerateToken(user.ID, 24*time.Hour, storage.ScopeAuthentication)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Keep the User-Agent so the user can tell their sessions apart when listing them.
+	token.UserAgent = r.UserAgent()
+	if len(token.UserAgent) > maxUserAgentBytes {
+		token.UserAgent = strings.ToValidUTF8(token.UserAgent[:maxUserAgentBytes], "")
+	}
+
+	err = app.Storage.Tokens.Insert(token)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -64,3 +80,52 @@ func (app *App) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// listAuthenticationTokensHandler returns the active sessions of the current user.
+func (app *App) listAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	tokens, err := app.Storage.Tokens.GetAllForUser(storage.ScopeAuthentication, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tokens": tokens}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteCurrentAuthenticationTokenHandler logs out by revoking the token the request was made with.
+func (app *App) deleteCurrentAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	app.deleteAuthenticationToken(w, r, app.contextGetToken(r).ID)
+}
+
+// deleteAuthenticationTokenHandler revokes another session of the current user, e.g. one on a
+// lost device.
+func (app *App) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.deleteAuthenticationToken(w, r, id)
+}
+
+func (app *App) deleteAuthenticationToken(w http.ResponseWriter, r *http.Request, id int64) {
+	err := app.Storage.Tokens.DeleteForUser(storage.ScopeAuthentication, id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "token successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+func TestSessionRoutesRejectAPIKeys(t *testing.T) {
+	app := newTestApp(t)
+
+	user, token := newTestUser(t, app, "user@example.com", model.RoleEditor)
+	key := newTestAPIKey(t, app, user, "shortenings:read")
+
+	other, err := app.Storage.Tokens.New(user.ID, time.Hour, storage.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/v1/tokens"},
+		{http.MethodDelete, "/api/v1/tokens/authentication"},
+		{http.MethodDelete, fmt.Sprintf("/api/v1/tokens/authentication/%d", other.ID)},
+	}
+
+	for _, route := range routes {
+		w := request(t, app, route.method, route.path, "ApiKey "+key, "")
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s with an API key: got status %d, want 403", route.method, route.path, w.Code)
+		}
+	}
+
+	w := request(t, app, http.MethodGet, "/api/v1/tokens", bearer(token), "")
+	checkStatus(t, w, http.StatusOK)
+
+	w = request(t, app, http.MethodDelete, fmt.Sprintf("/api/v1/tokens/authentication/%d", other.ID), bearer(token), "")
+	checkStatus(t, w, http.StatusOK)
+
+	w = request(t, app, http.MethodDelete, "/api/v1/tokens/authentication", bearer(token), "")
+	checkStatus(t, w, http.StatusOK)
+
+	// The token is gone after logging out.
+	w = request(t, app, http.MethodGet, "/api/v1/tokens", bearer(token), "")
+	checkStatus(t, w, http.StatusUnauthorized)
+}
+
+func TestDeleteSessionWithInvalidID(t *testing.T) {
+	app := newTestApp(t)
+	_, token := newTestUser(t, app, "user@example.com", model.RoleEditor)
+
+	w := request(t, app, http.MethodDelete, "/api/v1/tokens/authentication/abc", bearer(token), "")
+	checkStatus(t, w, http.StatusBadRequest)
+
+	w = request(t, app, http.MethodDelete, "/api/v1/tokens/authentication/12345", bearer(token), "")
+	checkStatus(t, w, http.StatusNotFound)
+}
@@ -27,7 +27,7 @@ func (app *App) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	user := &model.User{
 		Name:      input.Name,
-		Email:     input.Email,
+		Email:     model.NormalizeEmail(input.Email),
 		Activated: false,
 	}
 
@@ -58,7 +58,7 @@ func (app *App) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.Storage.Permissions.AddForUser(user.ID, "shortenings:read")
+	err = app.Storage.Permissions.AddRoleForUser(user.ID, model.RoleEditor)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -152,3 +152,42 @@ func (app *App) activateUserHandler(w http.ResponseWriter, r *http.Request) {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// updateUserHandler changes the settings of a user, for now the default of the dedupe option of
+// new shortenings.
+func (app *App) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Dedupe *bool `json:"dedupe"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Dedupe != nil {
+		user.Dedupe = *input.Dedupe
+	}
+
+	err = app.Storage.Users.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
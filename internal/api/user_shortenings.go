@@ -3,10 +3,11 @@ package api
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/yantay0/url-shortener/internal/model"
 	"github.com/yantay0/url-shortener/internal/storage"
 	"github.com/yantay0/url-shortener/internal/validator"
@@ -39,8 +40,14 @@ func (app *App) createShorteningFromURLHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	var input struct {
-		OriginalURL string `json:"original_url"`
-		Identifier  string `json:"identifier,omitempty"` // Make Identifier optional
+		OriginalURL  string     `json:"original_url"`
+		Identifier   string     `json:"identifier,omitempty"` // Make Identifier optional
+		RedirectType int        `json:"redirect_type,omitempty"`
+		ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+		MaxVisits    *int64     `json:"max_visits,omitempty"`
+		Password     *string    `json:"password,omitempty"`
+		Dedupe       *bool      `json:"dedupe,omitempty"` // Return the existing shortening if the user already shortened the URL, the user's default if missing.
+		Domain       string     `json:"domain,omitempty"` // Verified custom domain of the user, the default domain if empty.
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -49,64 +56,260 @@ func (app *App) createShorteningFromURLHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Check if Identifier is provided in the request
-	if input.Identifier == "" {
-		// If not provided, generate a new identifier server-side
-		shorten := model.GenerateShortening()
-		input.Identifier = shorten
+	shortening := &model.Shortening{
+		Identifier:   input.Identifier,
+		OriginalURL:  input.OriginalURL,
+		UserID:       userID,
+		RedirectType: input.RedirectType,
+		ExpiresAt:    input.ExpiresAt,
+		MaxVisits:    input.MaxVisits,
 	}
 
-	baseURL := fmt.Sprintf("http://%s:%s", app.Config.HTTPServer.IpAdress, app.Config.HTTPServer.Port) // Fixed typo in IpAddress
+	v := validator.New()
+	if input.Identifier != "" {
+		validator.ValidateAlias(v, input.Identifier, app.aliasRules())
+	}
+	model.ValidateShortening(v, shortening)
+	model.ValidateExpiration(v, shortening)
+	if input.Password != nil {
+		model.ValidatePasswordPlaintext(v, *input.Password)
+	}
+	if input.Domain != "" {
+		shortening.Domain, err = app.userDomain(v, userID, input.Domain)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
-	shortURL, err := model.PrependBaseURL(baseURL, input.Identifier)
+	app.checkURLSafety(r, shortening)
+	if shortening.SafetyVerdict == model.VerdictFlagged {
+		v.AddError("original_url", "is flagged as unsafe: "+shortening.SafetyReason)
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	dedupe, err := app.dedupeDefault(r, userID, input.Dedupe)
 	if err != nil {
-		log.Printf("error generating full URL: %v", err)
-		app.badRequestResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	shortening := &model.Shortening{
-		Identifier:  input.Identifier,
-		OriginalURL: input.OriginalURL,
-		UserID:      userID,
+	if dedupe {
+		urlHash, err := model.HashURL(shortening.OriginalURL)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		existing, err := app.Storage.Shortenings.GetByURLHash(userID, shortening.Domain, urlHash)
+		switch {
+		case err == nil:
+			app.writeShortURLResponse(w, r, http.StatusOK, existing)
+			return
+		case !errors.Is(err, storage.ErrRecordNotFound):
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		shortening.URLHash = &urlHash
+	}
+
+	if input.Password != nil {
+		err = shortening.Password.Set(*input.Password)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// Check if Identifier is provided in the request, if not generate one server-side
+	if input.Identifier != "" {
+		err = app.Storage.Shortenings.SaveUserShortening(shortening)
+	} else {
+		err = app.saveWithGeneratedIdentifier(shortening)
 	}
-	v := validator.New()
-	err = app.Storage.Shortenings.SaveUserShortening(shortening)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrIdentifierExists):
-			v.AddError("message", "identifier already exists")
+			v.AddError("identifier", "already exists")
 			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, storage.ErrDuplicateURL):
+			// A concurrent request shortened the same URL in the meantime.
+			existing, err := app.Storage.Shortenings.GetByURLHash(userID, shortening.Domain, *shortening.URLHash)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			app.writeShortURLResponse(w, r, http.StatusOK, existing)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	app.fetchPreview(shortening)
+
+	app.writeShortURLResponse(w, r, http.StatusCreated, shortening)
+}
+
+// shortURL returns the full short URL of the shortening under the public base URL, on its custom
+// domain if it has one.
+func (app *App) shortURL(shortening *model.Shortening) (string, error) {
+	return model.PrependBaseURL(app.Config.HTTPServer.PublicBaseURL, shortening.Domain, shortening.Identifier)
+}
+
+// shorteningLocation returns the absolute URL of the API resource of the shortening for the
+// Location header of responses creating one.
+func (app *App) shorteningLocation(shortening *model.Shortening) string {
+	location := app.Config.HTTPServer.PublicBaseURL + BASE_URL + "/shortenings/" + url.PathEscape(shortening.Identifier)
+	if shortening.Domain != "" {
+		location += "?domain=" + url.QueryEscape(shortening.Domain)
+	}
+
+	return location
+}
+
+// writeShortURLResponse writes the full short URL of the shortening with the given status.
+func (app *App) writeShortURLResponse(w http.ResponseWriter, r *http.Request, status int, shortening *model.Shortening) {
+	shortURL, err := app.shortURL(shortening)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("api/v1/shorternings/%s", shortening.Identifier))
+	headers.Set("Location", app.shorteningLocation(shortening))
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"short_url": shortURL}, nil)
+	err = app.writeJSON(w, status, envelope{"short_url": shortURL}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
+}
+
+// dedupeDefault returns the dedupe option of a new shortening of the user, the user's default if
+// the request doesn't set it.
+func (app *App) dedupeDefault(r *http.Request, userID int64, dedupe *bool) (bool, error) {
+	if dedupe != nil {
+		return *dedupe, nil
+	}
 
+	// Admins may create shortenings for other users, whose default applies then.
+	user := app.contextGetUser(r)
+	if user.ID != userID {
+		var err error
+		user, err = app.Storage.Users.GetByID(userID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return user.Dedupe, nil
+}
+
+// saveWithGeneratedIdentifier saves the shortening under a generated identifier and retries with
+// a new one when the identifier is already taken.
+func (app *App) saveWithGeneratedIdentifier(shortening *model.Shortening) error {
+	reserved := app.aliasRules().Reserved
+
+	for attempt := 0; attempt < app.Config.Identifier.MaxAttempts; attempt++ {
+		identifier, err := app.Identifiers.Generate(shortening.OriginalURL, attempt)
+		if err != nil {
+			return err
+		}
+
+		// A generated identifier could in theory spell a route segment or a reserved word.
+		if validator.Reserved(identifier, reserved...) {
+			continue
+		}
+
+		shortening.Identifier = identifier
+
+		err = app.Storage.Shortenings.SaveUserShortening(shortening)
+		if !errors.Is(err, storage.ErrIdentifierExists) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("no unique identifier generated in %d attempts", app.Config.Identifier.MaxAttempts)
 }
 
 func (app *App) redirectHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	identifier := vars["identifier"]
+	identifier := app.readIdentifierParam(r)
+
+	// Requests on a verified custom domain resolve the links of that domain.
+	domain, err := app.domainForHost(r.Host)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// A trailing '+' asks for the preview page instead of the redirect, like bit.ly. Aliases
+	// can't contain '+', so this doesn't clash with any identifier.
+	if strings.HasSuffix(identifier, "+") && r.Method == http.MethodGet {
+		app.previewHandler(w, r, domain, strings.TrimSuffix(identifier, "+"))
+		return
+	}
 
-	shortening, err := app.Storage.Shortenings.GetOriginalUrl(identifier)
+	shortening, err := app.Storage.Shortenings.GetOriginalUrl(domain, identifier)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
+		case errors.Is(err, storage.ErrLinkExpired):
+			app.linkExpiredResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	http.Redirect(w, r, shortening.OriginalURL, http.StatusMovedPermanently)
+	status := shortening.RedirectType
+	if status == 0 {
+		status = app.Config.HTTPServer.RedirectType
+	}
+
+	if shortening.IsProtected() {
+		if !app.unlockShortening(w, r, shortening) {
+			return
+		}
+
+		// The unlock form is submitted with POST, make sure the browser follows up with a GET.
+		if r.Method == http.MethodPost {
+			status = http.StatusSeeOther
+		}
+	}
+
+	if app.Config.Safety.CheckOnRedirect {
+		app.recheckURLSafety(r, shortening)
+	}
+
+	if shortening.SafetyVerdict == model.VerdictFlagged {
+		app.unsafeLinkResponse(w, r, shortening)
+		return
+	}
+
+	// The visits of links with max_visits are counted right away, the batches of the click
+	// recorder would let them go past the limit.
+	counted := shortening.MaxVisits != nil
+	if counted {
+		err = app.Storage.Shortenings.CountVisit(domain, identifier)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrLinkExpired):
+				app.linkExpiredResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	app.recordClick(r, shortening, counted)
+
+	http.Redirect(w, r, shortening.OriginalURL, status)
 }
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+// sequenceOf generates the given identifiers one after the other.
+type sequenceOf []string
+
+func (s *sequenceOf) Generate(originalURL string, attempt int) (string, error) {
+	identifier := (*s)[0]
+	*s = (*s)[1:]
+	return identifier, nil
+}
+
+func TestSaveWithGeneratedIdentifierSkipsReservedWords(t *testing.T) {
+	app := newTestApp(t)
+	app.Config.Alias.Reserved = []string{"admin"}
+	app.Identifiers = &sequenceOf{"API", "Admin", "abcdefg"}
+
+	shortening := &model.Shortening{OriginalURL: "https://example.com/", UserID: 1}
+
+	err := app.saveWithGeneratedIdentifier(shortening)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shortening.Identifier != "abcdefg" {
+		t.Errorf("got identifier %q, want the reserved words skipped", shortening.Identifier)
+	}
+}
+
+func TestSaveSameURLWithHashIdentifiers(t *testing.T) {
+	app := newTestApp(t)
+	app.Identifiers = model.HashGenerator{Length: 7}
+
+	// Every shortening of the URL after the first collides with the hash of the URL.
+	for i := 0; i <= 2*app.Config.Identifier.MaxAttempts; i++ {
+		shortening := &model.Shortening{OriginalURL: "https://example.com/", UserID: 1}
+
+		err := app.saveWithGeneratedIdentifier(shortening)
+		if err != nil {
+			t.Fatalf("shortening %d: %v", i, err)
+		}
+	}
+}
+
+func TestDedupeDefaultsToUserSetting(t *testing.T) {
+	app := newTestApp(t)
+	user, token := newTestUser(t, app, "owner@example.com", model.RoleEditor)
+	path := fmt.Sprintf("/api/v1/users/%d/shortenings", user.ID)
+	body := `{"original_url": "https://example.com/"}`
+
+	// Without the setting every request creates a new shortening.
+	w := request(t, app, http.MethodPost, path, bearer(token), body)
+	checkStatus(t, w, http.StatusCreated)
+
+	w = request(t, app, http.MethodPatch, fmt.Sprintf("/api/v1/users/%d", user.ID), bearer(token), `{"dedupe": true}`)
+	checkStatus(t, w, http.StatusOK)
+
+	w = request(t, app, http.MethodPost, path, bearer(token), body)
+	checkStatus(t, w, http.StatusCreated)
+
+	// The dedupe default returns the shortening created with it.
+	w = request(t, app, http.MethodPost, path, bearer(token), body)
+	checkStatus(t, w, http.StatusOK)
+
+	// An explicit false in the request overrides the default.
+	w = request(t, app, http.MethodPost, path, bearer(token), `{"original_url": "https://example.com/", "dedupe": false}`)
+	checkStatus(t, w, http.StatusCreated)
+}
+
+func TestDedupeIsPerDomain(t *testing.T) {
+	app := newTestApp(t)
+	user, token := newTestUser(t, app, "owner@example.com", model.RoleEditor)
+	path := fmt.Sprintf("/api/v1/users/%d/shortenings", user.ID)
+
+	domain, err := model.NewDomain(user.ID, "go.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Storage.Domains.Insert(domain); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Storage.Domains.MarkVerified(domain); err != nil {
+		t.Fatal(err)
+	}
+
+	w := request(t, app, http.MethodPost, path, bearer(token), `{"original_url": "https://example.com/", "dedupe": true}`)
+	checkStatus(t, w, http.StatusCreated)
+
+	// The link on the default domain is no use on the custom one.
+	body := `{"original_url": "https://example.com/", "dedupe": true, "domain": "go.example.com"}`
+	w = request(t, app, http.MethodPost, path, bearer(token), body)
+	checkStatus(t, w, http.StatusCreated)
+
+	w = request(t, app, http.MethodPost, path, bearer(token), body)
+	checkStatus(t, w, http.StatusOK)
+}
+
+func TestRedirectEnforcesMaxVisits(t *testing.T) {
+	app := newTestApp(t)
+	user, token := newTestUser(t, app, "owner@example.com", model.RoleEditor)
+	path := fmt.Sprintf("/api/v1/users/%d/shortenings", user.ID)
+
+	w := request(t, app, http.MethodPost, path, bearer(token), `{"original_url": "https://example.com/", "identifier": "once", "max_visits": 1}`)
+	checkStatus(t, w, http.StatusCreated)
+
+	// The visit is counted on redirect, without waiting for the click recorder to flush.
+	w = request(t, app, http.MethodGet, "/once", "", "")
+	checkStatus(t, w, app.Config.HTTPServer.RedirectType)
+
+	w = request(t, app, http.MethodGet, "/once", "", "")
+	checkStatus(t, w, http.StatusGone)
+}
+
+func TestUpdateUserByRole(t *testing.T) {
+	app := newTestApp(t)
+	user, token := newTestUser(t, app, "owner@example.com", model.RoleViewer)
+	_, otherToken := newTestUser(t, app, "other@example.com", model.RoleEditor)
+	_, adminToken := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+	key := newTestAPIKey(t, app, user, "shortenings:read")
+
+	path := fmt.Sprintf("/api/v1/users/%d", user.ID)
+	body := `{"dedupe": true}`
+
+	checkStatus(t, request(t, app, http.MethodPatch, path, bearer(otherToken), body), http.StatusForbidden)
+	checkStatus(t, request(t, app, http.MethodPatch, path, "ApiKey "+key, body), http.StatusForbidden)
+	checkStatus(t, request(t, app, http.MethodPatch, path, bearer(token), body), http.StatusOK)
+	checkStatus(t, request(t, app, http.MethodPatch, path, bearer(adminToken), `{"dedupe": false}`), http.StatusOK)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+func TestEmailsAreCaseInsensitive(t *testing.T) {
+	app := newTestApp(t)
+
+	w := request(t, app, http.MethodPost, "/api/v1/users", "", `{"name": "Alice", "email": "Alice@Example.COM", "password": "pa55word123"}`)
+	checkStatus(t, w, http.StatusAccepted)
+
+	user, err := app.Storage.Users.GetByEmail("alice@example.com")
+	if err != nil {
+		t.Fatalf("registered user isn't found by the lowercase email: %v", err)
+	}
+	if user.Email != "alice@example.com" {
+		t.Errorf("got stored email %q, want it lowercased", user.Email)
+	}
+
+	w = request(t, app, http.MethodPost, "/api/v1/users", "", `{"name": "Alice", "email": "ALICE@example.com", "password": "pa55word123"}`)
+	checkStatus(t, w, http.StatusUnprocessableEntity)
+
+	w = request(t, app, http.MethodPost, "/api/v1/tokens/authentication", "", `{"email": "aLiCe@example.com", "password": "pa55word123"}`)
+	checkStatus(t, w, http.StatusCreated)
+}
+
+func TestRegisteredUserCanCreateShortenings(t *testing.T) {
+	app := newTestApp(t)
+
+	w := request(t, app, http.MethodPost, "/api/v1/users", "", `{"name": "Bob", "email": "bob@example.com", "password": "pa55word123"}`)
+	checkStatus(t, w, http.StatusAccepted)
+
+	user, err := app.Storage.Users.GetByEmail("bob@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The plaintext of the mailed activation token is only known to the mailer, a second one
+	// activates the user as well.
+	activation, err := app.Storage.Tokens.New(user.ID, time.Hour, storage.ScopeActivation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w = request(t, app, http.MethodPut, "/api/v1/users/activated", "", fmt.Sprintf(`{"token": %q}`, activation.Plaintext))
+	checkStatus(t, w, http.StatusOK)
+
+	w = request(t, app, http.MethodPost, "/api/v1/tokens/authentication", "", `{"email": "bob@example.com", "password": "pa55word123"}`)
+	checkStatus(t, w, http.StatusCreated)
+
+	var body struct {
+		Token struct {
+			Token string `json:"token"`
+		} `json:"authentication_token"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w = request(t, app, http.MethodPost, fmt.Sprintf("/api/v1/users/%d/shortenings", user.ID), bearer(body.Token.Token), `{"original_url": "https://example.com/"}`)
+	checkStatus(t, w, http.StatusCreated)
+}
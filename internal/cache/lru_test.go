@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](2, time.Hour)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	// Reading a makes b the least recently used entry.
+	if _, found := c.Get("a"); !found {
+		t.Fatal("a not found")
+	}
+	c.Set("c", 3)
+
+	if _, found := c.Get("b"); found {
+		t.Error("b found, want it evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if got, found := c.Get(key); !found || got != want {
+			t.Errorf("got %d, %t for %s, want %d", got, found, key, want)
+		}
+	}
+
+	// Replacing a value also counts as a use.
+	c.Set("a", 10)
+	c.Set("d", 4)
+
+	if _, found := c.Get("c"); found {
+		t.Error("c found, want it evicted")
+	}
+	if got, _ := c.Get("a"); got != 10 {
+		t.Errorf("got %d for a, want the replaced value 10", got)
+	}
+}
+
+func TestLRUExpires(t *testing.T) {
+	c := New[string, int](10, 20*time.Millisecond)
+
+	c.Set("a", 1)
+	if _, found := c.Get("a"); !found {
+		t.Fatal("a not found before its TTL")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if _, found := c.Get("a"); found {
+		t.Error("a found after its TTL")
+	}
+	if size := c.Stats().Size; size != 0 {
+		t.Errorf("got size %d, want the expired entry removed", size)
+	}
+}
+
+func TestLRUStats(t *testing.T) {
+	c := New[string, int](10, time.Hour)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Get("a")
+	c.Get("missing")
+	c.Delete("b")
+
+	want := Stats{Size: 1, Hits: 2, Misses: 1}
+	if got := c.Stats(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	c.Purge()
+	if size := c.Stats().Size; size != 0 {
+		t.Errorf("got size %d after Purge, want 0", size)
+	}
+}
+
+func TestDisabledLRU(t *testing.T) {
+	c := New[string, int](0, time.Hour)
+	if c != nil {
+		t.Fatal("got a cache for size 0, want nil")
+	}
+
+	// The nil cache is usable and always empty.
+	c.Set("a", 1)
+	if _, found := c.Get("a"); found {
+		t.Error("a found in the disabled cache")
+	}
+	if got := c.Stats(); got != (Stats{}) {
+		t.Errorf("got %+v, want empty stats", got)
+	}
+}
+
+func TestLRUDeleteFunc(t *testing.T) {
+	c := New[string, int](10, time.Hour)
+
+	for i, key := range []string{"a/1", "a/2", "b/1"} {
+		c.Set(key, i)
+	}
+
+	c.DeleteFunc(func(key string) bool { return key[0] == 'a' })
+
+	for _, key := range []string{"a/1", "a/2"} {
+		if _, found := c.Get(key); found {
+			t.Errorf("%s found, want it deleted", key)
+		}
+	}
+	if _, found := c.Get("b/1"); !found {
+		t.Error("b/1 not found, want it kept")
+	}
+}
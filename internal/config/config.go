@@ -1,8 +1,14 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -14,6 +20,15 @@ type Config struct {
 	HTTPServer `yaml:"http_server"`
 	SMTP       `yaml:"smtp"`
 	Limiter    `yaml:"limiter"`
+	Sweeper    `yaml:"sweeper"`
+	Recorder   `yaml:"recorder"`
+	Cache      `yaml:"cache"`
+	Identifier `yaml:"identifier"`
+	Alias      `yaml:"alias"`
+	Safety     `yaml:"safety"`
+	LinkCheck  `yaml:"link_check"`
+	Preview    `yaml:"preview"`
+	Domains    `yaml:"domains"`
 }
 
 type SMTP struct {
@@ -25,23 +40,96 @@ type SMTP struct {
 }
 
 type DB struct {
-	Dsn          string `yaml:"dsn" env-required:"true"`
+	Driver       string `yaml:"driver" env-default:"postgres"` // "postgres", "sqlite" or "memory"
+	Dsn          string `yaml:"dsn"`
 	MaxOpenConns int    `yaml:"maxOpenConns" env-default:"25"`
 	MaxIdleConns int    `yaml:"maxIdleConns" env-default:"25"`
 	MaxIdleTime  string `yaml:"maxIdleTime" env-default:"15m"`
 }
 
 type HTTPServer struct {
-	IpAdress    string        `yaml:"ip_address" env-default:"localhost"`
-	Port        string        `yaml:"port" env-default:"8080"`
-	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	IpAdress     string        `yaml:"ip_address" env-default:"localhost"`
+	Port         string        `yaml:"port" env-default:"8080"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"4s"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	RedirectType int           `yaml:"redirect_type" env-default:"302"` // Used by shortenings without their own redirect_type
+	// Scheme, host and optional path prefix short links are built from, e.g. https://sho.rt/s when
+	// running behind a reverse proxy. Defaults to http://<ip_address>:<port>.
+	PublicBaseURL string `yaml:"public_base_url"`
 }
 
 type Limiter struct {
-	RPS     float64 `yaml:"rps" env-default:"2"` // Rate limiter maximum requests per second
-	Burst   int     `yaml:"burst" env-default:"4"`
-	Enabled bool    `yaml:"enabled" env-default:"true"`
+	RPS         float64 `yaml:"rps" env-default:"2"` // Rate limiter maximum requests per second
+	Burst       int     `yaml:"burst" env-default:"4"`
+	Enabled     bool    `yaml:"enabled" env-default:"true"`
+	UnlockRPS   float64 `yaml:"unlock_rps" env-default:"0.1"` // Wrong password attempts per second on a protected link
+	UnlockBurst int     `yaml:"unlock_burst" env-default:"5"`
+}
+
+type Sweeper struct {
+	Interval time.Duration `yaml:"interval" env-default:"5m"` // How often expired shortenings are archived
+	Enabled  bool          `yaml:"enabled" env-default:"true"`
+}
+
+type Recorder struct {
+	Workers       int           `yaml:"workers" env-default:"2"`
+	BufferSize    int           `yaml:"buffer_size" env-default:"10000"` // Clicks are dropped when the buffer is full
+	BatchSize     int           `yaml:"batch_size" env-default:"500"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"1s"`
+	// Key of the client IP hashes, a random one is generated on start if empty. Hashes can then
+	// only be linked until the next restart.
+	IPHashSecret   string        `yaml:"ip_hash_secret"`
+	IPHashRotation time.Duration `yaml:"ip_hash_rotation" env-default:"24h"` // Hashes of the same IP differ from one period to the next
+}
+
+type Cache struct {
+	Size            int           `yaml:"size" env-default:"10000"` // Maximum number of cached shortenings, 0 disables the cache
+	TTL             time.Duration `yaml:"ttl" env-default:"1m"`
+	PermissionsSize int           `yaml:"permissions_size" env-default:"10000"` // Maximum number of users with cached permissions, 0 disables the cache
+	PermissionsTTL  time.Duration `yaml:"permissions_ttl" env-default:"30s"`    // Bounds how long other instances keep serving revoked permissions
+	DomainsSize     int           `yaml:"domains_size" env-default:"1000"`      // Maximum number of cached host lookups, 0 disables the cache
+	DomainsTTL      time.Duration `yaml:"domains_ttl" env-default:"1m"`         // Bounds how long other instances keep resolving deleted domains
+}
+
+type Identifier struct {
+	Strategy    string `yaml:"strategy" env-default:"random"` // "random", "sequence" or "hash"
+	Length      int    `yaml:"length" env-default:"7"`        // Used by the random and hash strategies
+	MaxAttempts int    `yaml:"max_attempts" env-default:"5"`  // Generated identifiers are retried on collision
+}
+
+type Alias struct {
+	MinLength int      `yaml:"min_length" env-default:"3"`
+	MaxLength int      `yaml:"max_length" env-default:"32"`
+	Reserved  []string `yaml:"reserved" env-default:"admin,login,logout,static,assets,favicon.ico,robots.txt"` // Top-level route segments are always reserved
+}
+
+type Safety struct {
+	BlocklistPath   string        `yaml:"blocklist_path"`                        // Empty disables the blocklist
+	ReloadInterval  time.Duration `yaml:"reload_interval" env-default:"30s"`     // How often the blocklist file is checked for changes
+	SafeBrowsingKey string        `yaml:"safe_browsing_key"`                     // Empty disables the Safe Browsing lookup
+	Timeout         time.Duration `yaml:"timeout" env-default:"2s"`              // URLs are let through if the checks don't finish in time
+	CheckOnRedirect bool          `yaml:"check_on_redirect" env-default:"false"` // Check again on every redirect, flagged links show a warning page
+}
+
+type LinkCheck struct {
+	Enabled      bool          `yaml:"enabled" env-default:"false"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1m"` // How often due destinations are looked up
+	Interval     time.Duration `yaml:"interval" env-default:"6h"`      // How often a healthy destination is checked
+	MaxBackoff   time.Duration `yaml:"max_backoff" env-default:"72h"`  // Upper bound for rechecking unhealthy destinations
+	Concurrency  int           `yaml:"concurrency" env-default:"8"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+type Preview struct {
+	Enabled  bool          `yaml:"enabled" env-default:"true"` // Fetch the destination metadata shown on /:identifier+
+	Timeout  time.Duration `yaml:"timeout" env-default:"5s"`
+	MaxBytes int64         `yaml:"max_bytes" env-default:"524288"` // Only the beginning of the page is read
+}
+
+type Domains struct {
+	VerificationPrefix string        `yaml:"verification_prefix" env-default:"_url-shortener"` // Custom domains are verified with a TXT record at <prefix>.<host>
+	VerifyTimeout      time.Duration `yaml:"verify_timeout" env-default:"5s"`
 }
 
 func MustLoad() *Config {
@@ -61,5 +149,72 @@ func MustLoad() *Config {
 		log.Fatalf("cannot read config: %s", err)
 	}
 
+	publicBaseURL, err := parsePublicBaseURL(cfg.HTTPServer)
+	if err != nil {
+		log.Fatalf("invalid public_base_url: %s", err)
+	}
+	cfg.HTTPServer.PublicBaseURL = publicBaseURL
+
+	mustBePositive("recorder.workers", cfg.Recorder.Workers)
+	mustBePositive("recorder.buffer_size", cfg.Recorder.BufferSize)
+	mustBePositive("recorder.batch_size", cfg.Recorder.BatchSize)
+	mustBePositive("recorder.flush_interval", cfg.Recorder.FlushInterval)
+	mustBePositive("recorder.ip_hash_rotation", cfg.Recorder.IPHashRotation)
+
+	if cfg.Recorder.IPHashSecret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("cannot generate the ip hash secret: %s", err)
+		}
+		cfg.Recorder.IPHashSecret = hex.EncodeToString(secret)
+	}
+
+	if cfg.Sweeper.Enabled {
+		mustBePositive("sweeper.interval", cfg.Sweeper.Interval)
+	}
+
+	if cfg.LinkCheck.Enabled {
+		mustBePositive("link_check.poll_interval", cfg.LinkCheck.PollInterval)
+		mustBePositive("link_check.concurrency", cfg.LinkCheck.Concurrency)
+		mustBePositive("link_check.batch_size", cfg.LinkCheck.BatchSize)
+	}
+
+	mustBePositive("identifier.max_attempts", cfg.Identifier.MaxAttempts)
+
 	return &cfg
 }
+
+// mustBePositive stops the program if a config value that only makes sense above zero, e.g. a
+// ticker interval or a number of workers, isn't.
+func mustBePositive[T int | time.Duration](name string, value T) {
+	if value <= 0 {
+		log.Fatalf("invalid %s: must be greater than zero, got %v", name, value)
+	}
+}
+
+// parsePublicBaseURL checks the configured public base URL, or derives it from the listen
+// address, and returns it without a trailing slash.
+func parsePublicBaseURL(cfg HTTPServer) (string, error) {
+	if cfg.PublicBaseURL == "" {
+		return fmt.Sprintf("http://%s:%s", cfg.IpAdress, cfg.Port), nil
+	}
+
+	parsed, err := url.Parse(cfg.PublicBaseURL)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case parsed.Scheme != "http" && parsed.Scheme != "https":
+		return "", errors.New("must be an absolute http or https URL")
+	case parsed.Host == "":
+		return "", errors.New("must contain a host")
+	case parsed.RawQuery != "" || parsed.Fragment != "" || parsed.User != nil:
+		return "", errors.New("must not contain credentials, a query or a fragment")
+	}
+
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	parsed.RawPath = ""
+
+	return parsed.String(), nil
+}
@@ -0,0 +1,51 @@
+package dnsverify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Resolver looks up the TXT records of a DNS name. *net.Resolver implements it, a fake one can
+// be plugged in where no real DNS is available.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Verifier checks that the owner of a domain has published its verification token in a TXT
+// record at Prefix.host, e.g. _url-shortener.go.example.com.
+type Verifier struct {
+	Resolver Resolver
+	Prefix   string
+}
+
+func New(resolver Resolver, prefix string) *Verifier {
+	return &Verifier{Resolver: resolver, Prefix: prefix}
+}
+
+// RecordName returns the name of the TXT record the token of host has to be published at.
+func (v *Verifier) RecordName(host string) string {
+	return v.Prefix + "." + host
+}
+
+// Verify reports whether one of the TXT records at the record name of host holds token. A
+// missing record isn't an error, only failed lookups are.
+func (v *Verifier) Verify(ctx context.Context, host, token string) (bool, error) {
+	records, err := v.Resolver.LookupTXT(ctx, v.RecordName(host))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == token {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,153 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/lib/logger/jsonlog"
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/netguard"
+)
+
+// Store provides the shortenings due for a check and persists the outcome.
+type Store interface {
+	GetDueForHealthCheck(limit int) ([]*model.Shortening, error)
+	RecordHealth(shortening *model.Shortening) error
+}
+
+// Checker probes the destinations of shortenings and records whether they are still alive. A
+// healthy destination is checked again after interval, an unhealthy one backs off exponentially
+// up to maxBackoff, so dead links don't get hammered.
+type Checker struct {
+	store       Store
+	logger      *jsonlog.Logger
+	client      *http.Client
+	concurrency int
+	batchSize   int
+	interval    time.Duration
+	maxBackoff  time.Duration
+}
+
+func New(store Store, logger *jsonlog.Logger, concurrency, batchSize int, interval, timeout, maxBackoff time.Duration) *Checker {
+	return &Checker{
+		store:       store,
+		logger:      logger,
+		client:      netguard.NewClient(timeout), // The status is shown to the owner, internal ports mustn't be probed
+		concurrency: concurrency,
+		batchSize:   batchSize,
+		interval:    interval,
+		maxBackoff:  maxBackoff,
+	}
+}
+
+// RunOnce checks one batch of due destinations, at most concurrency at a time, and returns how
+// many were checked.
+func (c *Checker) RunOnce(ctx context.Context) (int, error) {
+	shortenings, err := c.store.GetDueForHealthCheck(c.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, c.concurrency)
+
+	for _, shortening := range shortenings {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(shortening *model.Shortening) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			c.check(ctx, shortening)
+		}(shortening)
+	}
+
+	wg.Wait()
+
+	return len(shortenings), nil
+}
+
+func (c *Checker) check(ctx context.Context, shortening *model.Shortening) {
+	status := c.probe(ctx, shortening.OriginalURL)
+	healthy := Healthy(status)
+	now := time.Now().Truncate(time.Second)
+
+	delay := c.interval
+	if healthy {
+		shortening.CheckFailures = 0
+	} else {
+		shortening.CheckFailures++
+		delay = c.backoff(shortening.CheckFailures)
+	}
+	next := now.Add(delay)
+
+	shortening.LastCheckedAt = &now
+	shortening.LastStatus = status
+	shortening.Healthy = &healthy
+	shortening.NextCheckAt = &next
+
+	err := c.store.RecordHealth(shortening)
+	if err != nil {
+		c.logger.PrintError(err, map[string]string{"identifier": shortening.Identifier})
+	}
+}
+
+// backoff doubles the interval for every consecutive failure, capped at maxBackoff.
+func (c *Checker) backoff(failures int) time.Duration {
+	delay := c.interval
+	for i := 1; i < failures && delay < c.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, c.maxBackoff)
+}
+
+// probe sends a HEAD request to rawURL, falling back to GET for servers that don't support HEAD,
+// and returns the final status code after redirects. It returns zero if the destination
+// couldn't be reached.
+func (c *Checker) probe(ctx context.Context, rawURL string) int {
+	status := c.request(ctx, http.MethodHead, rawURL)
+	if status == 0 || status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented {
+		status = c.request(ctx, http.MethodGet, rawURL)
+	}
+
+	return status
+}
+
+func (c *Checker) request(ctx context.Context, method, rawURL string) int {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0
+	}
+	req.Header.Set("User-Agent", "url-shortener-linkcheck/1.0")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0
+	}
+	// The body isn't needed, closing it without reading avoids downloading large pages.
+	res.Body.Close()
+
+	return res.StatusCode
+}
+
+// Healthy reports whether a destination that answered with status is considered alive. Only
+// unreachable destinations, 404 and 410 responses and server errors count as dead, other client
+// errors are usually bot protection or authentication in front of a working page.
+func Healthy(status int) bool {
+	switch {
+	case status == 0:
+		return false
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return false
+	case status >= 500:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,180 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yantay0/url-shortener/internal/netguard"
+	"golang.org/x/net/html"
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+)
+
+// Metadata describes a destination page for link previews.
+type Metadata struct {
+	Title       string
+	Description string
+	Image       string // Absolute http(s) URL of the og:image, if any
+}
+
+// Fetcher downloads the head of HTML pages and extracts their metadata. Responses are cut off
+// after maxBytes and the whole request is bounded by timeout, so a slow or huge page can't hold
+// up the fetcher. Internal addresses are never fetched, the metadata is shown publicly.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewFetcher(timeout time.Duration, maxBytes int64) *Fetcher {
+	return &Fetcher{
+		client:   netguard.NewClient(timeout),
+		maxBytes: maxBytes,
+	}
+}
+
+// Fetch returns the title, description and og:image of the page at rawURL. The Open Graph tags
+// take precedence over <title> and the description meta tag.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("User-Agent", "url-shortener-preview/1.0")
+	req.Header.Set("Accept", "text/html")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Metadata{}, fmt.Errorf("fetch metadata: unexpected status %s", res.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Metadata{}, fmt.Errorf("fetch metadata: unsupported content type %q", mediaType)
+	}
+
+	md := parse(io.LimitReader(res.Body, f.maxBytes))
+
+	// Relative og:image URLs are resolved against the final URL after redirects.
+	if md.Image != "" {
+		md.Image = resolveImage(res.Request.URL, md.Image)
+	}
+
+	return md, nil
+}
+
+// parse extracts the metadata from the <head> of an HTML document. It stops at <body>, the
+// metadata never lives there.
+func parse(r io.Reader) Metadata {
+	var md, og Metadata
+
+	z := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF or the size limit, use what has been found so far.
+			return merge(og, md)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+
+			switch token.Data {
+			case "body":
+				return merge(og, md)
+			case "title":
+				inTitle = true
+			case "meta":
+				name, property, content := "", "", ""
+				for _, attr := range token.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "name":
+						name = strings.ToLower(attr.Val)
+					case "property":
+						property = strings.ToLower(attr.Val)
+					case "content":
+						content = attr.Val
+					}
+				}
+
+				switch {
+				case name == "description":
+					md.Description = content
+				case property == "og:title":
+					og.Title = content
+				case property == "og:description":
+					og.Description = content
+				case property == "og:image" || property == "og:image:url":
+					if og.Image == "" {
+						og.Image = content
+					}
+				}
+			}
+		case html.TextToken:
+			if inTitle && md.Title == "" {
+				md.Title = string(z.Text())
+			}
+		case html.EndTagToken:
+			if z.Token().Data == "title" {
+				inTitle = false
+			}
+		}
+	}
+}
+
+// merge prefers the Open Graph values and cleans up the result.
+func merge(og, md Metadata) Metadata {
+	pick := func(a, b string) string {
+		if strings.TrimSpace(a) != "" {
+			return a
+		}
+		return b
+	}
+
+	return Metadata{
+		Title:       truncate(clean(pick(og.Title, md.Title)), maxTitleLength),
+		Description: truncate(clean(pick(og.Description, md.Description)), maxDescriptionLength),
+		Image:       strings.TrimSpace(og.Image),
+	}
+}
+
+// clean collapses the whitespace in s.
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+func resolveImage(base *url.URL, image string) string {
+	ref, err := url.Parse(image)
+	if err != nil {
+		return ""
+	}
+
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+
+	return resolved.String()
+}
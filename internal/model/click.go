@@ -0,0 +1,147 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/validator"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// StatsIntervals holds the bucket sizes supported by the click statistics.
+var StatsIntervals = []string{"hour", "day", "week"}
+
+// Click is a single visit of a short link.
+type Click struct {
+	ID         int64     `json:"id"`
+	Identifier string    `json:"identifier"`
+	Domain     string    `json:"domain,omitempty"`
+	ClickedAt  time.Time `json:"clicked_at"`
+	Referrer   string    `json:"referrer,omitempty"`
+	UAFamily   string    `json:"ua_family"`
+	Device     string    `json:"device"`
+	IPHash     string    `json:"-"` // The client IP is never stored, only its keyed hash, see IPHasher.
+	// Counted is set when the visit was already counted on redirect, for links with max_visits.
+	Counted bool `json:"-"`
+}
+
+// ClickBucket holds the number of clicks in the interval starting at Start.
+type ClickBucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+}
+
+// ClickCount holds the number of clicks grouped by a single value, e.g. a referrer.
+type ClickCount struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
+
+type ClickStats struct {
+	Interval   string         `json:"interval"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Total      int64          `json:"total"`
+	Buckets    []*ClickBucket `json:"buckets"`
+	Referrers  []*ClickCount  `json:"referrers"`
+	UAFamilies []*ClickCount  `json:"ua_families"`
+	Devices    []*ClickCount  `json:"devices"`
+}
+
+// uaFamilies is checked in order, so more specific tokens must come before the generic ones
+// (e.g. Edge and Opera also send "Chrome/", and Chrome also sends "Safari/").
+var uaFamilies = []struct {
+	token  string
+	family string
+}{
+	{"edg/", "Edge"},
+	{"opr/", "Opera"},
+	{"yabrowser/", "Yandex"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"chrome/", "Chrome"},
+	{"crios/", "Chrome"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"safari/", "Safari"},
+	{"trident/", "Internet Explorer"},
+	{"msie ", "Internet Explorer"},
+	{"curl/", "curl"},
+	{"wget/", "Wget"},
+}
+
+var botTokens = []string{"bot", "crawler", "spider", "slurp", "curl/", "wget/", "python-requests", "go-http-client"}
+
+// ParseUserAgent returns the browser family and the device type of a User-Agent header.
+func ParseUserAgent(userAgent string) (family, device string) {
+	ua := strings.ToLower(userAgent)
+
+	family = "Other"
+	for _, f := range uaFamilies {
+		if strings.Contains(ua, f.token) {
+			family = f.family
+			break
+		}
+	}
+
+	switch {
+	case containsAny(ua, botTokens...):
+		device = DeviceBot
+	case containsAny(ua, "ipad", "tablet"):
+		device = DeviceTablet
+	case containsAny(ua, "mobi", "iphone", "android"):
+		device = DeviceMobile
+	default:
+		device = DeviceDesktop
+	}
+
+	return family, device
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// IPHasher hashes client IPs with HMAC-SHA-256. A plain hash of an IP is easily reversed by
+// hashing every IPv4 address, without Secret that isn't possible. The key is derived from Secret
+// and the current period of length Rotation, so hashes of different periods can't be linked.
+type IPHasher struct {
+	Secret   []byte
+	Rotation time.Duration // Zero never rotates the key
+}
+
+// Hash returns the hex encoded hash of the IP address seen at the given time.
+func (h IPHasher) Hash(ip string, at time.Time) string {
+	var period int64
+	if h.Rotation > 0 {
+		period = at.UnixNano() / int64(h.Rotation)
+	}
+
+	mac := hmac.New(sha256.New, h.Secret)
+	mac.Write([]byte(strconv.FormatInt(period, 10)))
+	key := mac.Sum(nil)
+
+	mac = hmac.New(sha256.New, key)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func ValidateStatsRange(v *validator.Validator, interval string, from, to time.Time) {
+	v.Check(validator.In(interval, StatsIntervals...), "interval", "must be one of hour, day or week")
+	v.Check(from.Before(to), "from", "must be before to")
+	v.Check(to.Sub(from) <= 366*24*time.Hour, "from", "range must not be longer than a year")
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestIPHasher(t *testing.T) {
+	h := IPHasher{Secret: []byte("secret"), Rotation: 24 * time.Hour}
+	morning := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	evening := morning.Add(12 * time.Hour)
+	nextDay := morning.Add(24 * time.Hour)
+
+	hash := h.Hash("203.0.113.7", morning)
+
+	if got := h.Hash("203.0.113.7", evening); got != hash {
+		t.Errorf("got %s and %s for the same IP on the same day, want the same hash", hash, got)
+	}
+	if got := h.Hash("203.0.113.8", morning); got == hash {
+		t.Error("got the same hash for another IP")
+	}
+	if got := h.Hash("203.0.113.7", nextDay); got == hash {
+		t.Error("got the same hash after the key rotated")
+	}
+
+	other := IPHasher{Secret: []byte("other"), Rotation: 24 * time.Hour}
+	if got := other.Hash("203.0.113.7", morning); got == hash {
+		t.Error("got the same hash with another secret")
+	}
+
+	// The IP can't be recovered by hashing every address.
+	plain := sha256.Sum256([]byte("203.0.113.7"))
+	if hash == hex.EncodeToString(plain[:]) {
+		t.Error("got the plain SHA-256 hash of the IP")
+	}
+}
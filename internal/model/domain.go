@@ -0,0 +1,84 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/validator"
+	"golang.org/x/net/idna"
+)
+
+// Domain is a custom host name a user can create short links on, e.g. go.example.com. Links are
+// only created on a domain once its owner has proven control over it with a DNS TXT record.
+type Domain struct {
+	ID                int64      `json:"id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Host              string     `json:"host"` // Lowercase punycode host name without port
+	UserID            int64      `json:"user_id"`
+	VerificationToken string     `json:"verification_token"` // Expected value of the TXT record
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+}
+
+// NewDomain returns an unverified domain of the user with a random verification token.
+func NewDomain(userID int64, host string) (*Domain, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Domain{
+		Host:              host,
+		UserID:            userID,
+		VerificationToken: base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes),
+	}, nil
+}
+
+// IsVerified reports whether the owner has proven control over the domain.
+func (d *Domain) IsVerified() bool {
+	return d.VerifiedAt != nil
+}
+
+// ValidateDomain checks the host of a new domain and replaces it by its normalized form.
+func ValidateDomain(v *validator.Validator, domain *Domain) {
+	host := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain.Host)), ".")
+
+	v.Check(host != "", "host", "must be provided")
+	v.Check(len(host) <= 253, "host", "must not be more than 253 bytes long")
+	if host == "" || len(host) > 253 {
+		return
+	}
+
+	if strings.ContainsAny(host, "/:@") {
+		v.AddError("host", "must be a host name without scheme, port or path")
+		return
+	}
+
+	if net.ParseIP(host) != nil {
+		v.AddError("host", "must not be an IP address")
+		return
+	}
+
+	host, err := idna.Registration.ToASCII(host)
+	if err != nil || !strings.Contains(host, ".") {
+		v.AddError("host", "must be a valid host name")
+		return
+	}
+
+	domain.Host = host
+}
+
+// HostName returns the lowercase host of a Host header value without the port, in the form
+// domains are stored in.
+func HostName(hostport string) string {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,152 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/yantay0/url-shortener/internal/util"
+)
+
+const (
+	StrategyRandom   = "random"
+	StrategySequence = "sequence"
+	StrategyHash     = "hash"
+)
+
+// IdentifierGenerator generates the identifiers of shortenings that have no custom alias.
+// Generated identifiers may still collide with existing ones, so callers retry with an
+// increasing attempt number; deterministic strategies use it to produce another identifier.
+type IdentifierGenerator interface {
+	Generate(originalURL string, attempt int) (string, error)
+}
+
+// Sequence hands out unique, increasing numbers, e.g. from a database sequence.
+type Sequence interface {
+	NextID() (int64, error)
+}
+
+// NewIdentifierGenerator returns the generator of the given strategy. The length applies to the
+// random and hash strategies, the sequence strategy needs seq.
+func NewIdentifierGenerator(strategy string, length int, seq Sequence) (IdentifierGenerator, error) {
+	// 43 is the number of digits of a SHA-256 hash in the base of the alphabet.
+	if strategy != StrategySequence && (length < 4 || length > 43) {
+		return nil, fmt.Errorf("identifier length must be between 4 and 43, got %d", length)
+	}
+
+	switch strategy {
+	case StrategyRandom:
+		return RandomGenerator{Length: length}, nil
+	case StrategySequence:
+		if seq == nil {
+			return nil, fmt.Errorf("the %q identifier strategy needs a sequence", strategy)
+		}
+		return SequenceGenerator{Sequence: seq}, nil
+	case StrategyHash:
+		return HashGenerator{Length: length}, nil
+	default:
+		return nil, fmt.Errorf("unknown identifier strategy %q", strategy)
+	}
+}
+
+// RandomGenerator picks Length characters of the alphabet with crypto/rand. With the 58
+// characters alphabet, 7 characters give about 2.2e12 identifiers.
+type RandomGenerator struct {
+	Length int
+}
+
+func (g RandomGenerator) Generate(originalURL string, attempt int) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+
+	var builder strings.Builder
+	for i := 0; i < g.Length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteByte(alphabet[n.Int64()])
+	}
+
+	return builder.String(), nil
+}
+
+// SequenceGenerator encodes the next number of a sequence, so identifiers never collide with
+// each other, only with custom aliases. They are short but sequential, hence guessable.
+type SequenceGenerator struct {
+	Sequence Sequence
+}
+
+func (g SequenceGenerator) Generate(originalURL string, attempt int) (string, error) {
+	id, err := g.Sequence.NextID()
+	if err != nil {
+		return "", err
+	}
+
+	return encode(uint64(id)), nil
+}
+
+// HashGenerator derives the identifier from the SHA-256 hash of the URL, so the same URL gets
+// the same identifier on the first attempt. Further attempts hash the URL with a random salt
+// appended, otherwise a URL shortened more than the maximum number of attempts would run out of
+// identifiers.
+type HashGenerator struct {
+	Length int
+}
+
+func (g HashGenerator) Generate(originalURL string, attempt int) (string, error) {
+	input := []byte(originalURL)
+	if attempt > 0 {
+		salt := make([]byte, 16)
+		_, err := rand.Read(salt)
+		if err != nil {
+			return "", err
+		}
+		input = append(append(input, '#'), salt...)
+	}
+
+	hash := sha256.Sum256(input)
+
+	// The least significant digits of the 256-bit hash in the base of the alphabet are uniformly
+	// distributed, there are 43 of them.
+	var (
+		num   = new(big.Int).SetBytes(hash[:])
+		base  = big.NewInt(int64(len(alphabet)))
+		digit = new(big.Int)
+		id    = make([]byte, 0, g.Length)
+	)
+
+	for len(id) < g.Length && num.Sign() > 0 {
+		num.DivMod(num, base, digit)
+		id = append(id, alphabet[digit.Int64()])
+	}
+
+	return string(id), nil
+}
+
+// encode returns num in the base of the alphabet.
+func encode(num uint64) string {
+	if num == 0 {
+		return string(alphabet[0])
+	}
+
+	var (
+		digits  []uint64
+		base    = uint64(len(alphabet))
+		builder strings.Builder
+	)
+
+	for num > 0 {
+		digits = append(digits, num%base)
+		num /= base
+	}
+
+	util.Reverse(digits)
+
+	for _, digit := range digits {
+		builder.WriteByte(alphabet[digit])
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// counter is a Sequence handing out the numbers after next.
+type counter struct {
+	next int64
+}
+
+func (c *counter) NextID() (int64, error) {
+	c.next++
+	return c.next, nil
+}
+
+// collisions generates n identifiers, the i-th one for the URL returned by urlOf, and counts
+// those that were generated before.
+func collisions(t *testing.T, g IdentifierGenerator, n int, urlOf func(i int) string) int {
+	t.Helper()
+
+	seen := make(map[string]bool, n)
+	count := 0
+
+	for i := 0; i < n; i++ {
+		id, err := g.Generate(urlOf(i), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Trim(id, alphabet) != "" {
+			t.Fatalf("identifier %q has characters outside the alphabet", id)
+		}
+
+		if seen[id] {
+			count++
+		}
+		seen[id] = true
+	}
+
+	return count
+}
+
+// expectedCollisions is the birthday bound of n uniformly distributed identifiers of the given
+// length.
+func expectedCollisions(n, length int) float64 {
+	space := math.Pow(float64(len(alphabet)), float64(length))
+	return float64(n) * float64(n) / (2 * space)
+}
+
+// checkCollisionRate fails if the number of collisions is far from what uniformly distributed
+// identifiers would give. The bounds are more than ten standard deviations wide.
+func checkCollisionRate(t *testing.T, got int, n, length int) {
+	t.Helper()
+
+	want := expectedCollisions(n, length)
+	if float64(got) < want/2 || float64(got) > want*2 {
+		t.Errorf("got %d collisions among %d identifiers of length %d, want about %.0f", got, n, length, want)
+	}
+}
+
+func TestRandomGeneratorCollisionRate(t *testing.T) {
+	const n, length = 100_000, 4
+
+	g, err := NewIdentifierGenerator(StrategyRandom, length, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := collisions(t, g, n, func(i int) string { return "https://example.com/" })
+	checkCollisionRate(t, got, n, length)
+}
+
+func TestHashGeneratorCollisionRate(t *testing.T) {
+	const n, length = 100_000, 4
+
+	g, err := NewIdentifierGenerator(StrategyHash, length, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := collisions(t, g, n, func(i int) string { return fmt.Sprintf("https://example.com/%d", i) })
+	checkCollisionRate(t, got, n, length)
+}
+
+func TestHashGeneratorAttempts(t *testing.T) {
+	g := HashGenerator{Length: 7}
+
+	first, _ := g.Generate("https://example.com/", 0)
+	again, _ := g.Generate("https://example.com/", 0)
+	if first != again || len(first) != 7 {
+		t.Fatalf("got %q and %q for the same URL, want the same 7 characters", first, again)
+	}
+
+	// Retries after a collision must not produce the colliding identifier again.
+	seen := map[string]bool{first: true}
+	for attempt := 1; attempt < 100; attempt++ {
+		id, _ := g.Generate("https://example.com/", attempt)
+		if seen[id] {
+			t.Fatalf("attempt %d repeated identifier %q", attempt, id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestSequenceGeneratorNeverCollides(t *testing.T) {
+	g, err := NewIdentifierGenerator(StrategySequence, 0, &counter{next: 999_999})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := collisions(t, g, 100_000, func(i int) string { return "https://example.com/" }); got != 0 {
+		t.Errorf("got %d collisions, want none", got)
+	}
+}
+
+func TestNewIdentifierGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		strategy string
+		length   int
+		seq      Sequence
+	}{
+		{StrategyRandom, 3, nil},
+		{StrategyHash, 44, nil},
+		{StrategySequence, 7, nil},
+		{"uuid", 7, nil},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewIdentifierGenerator(tt.strategy, tt.length, tt.seq); err == nil {
+			t.Errorf("NewIdentifierGenerator(%q, %d) returned no error", tt.strategy, tt.length)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package model
 
+import (
+	"regexp"
+
+	"github.com/yantay0/url-shortener/internal/validator"
+)
+
 type Permissions []string
 
 // Add a helper method to check whether the Permissions slice contains a specific
@@ -12,3 +18,31 @@ func (p Permissions) Include(code string) bool {
 	}
 	return false
 }
+
+// Restrict returns the permissions of a user that an API key is allowed to use.
+func (p Permissions) Restrict(allowed Permissions) Permissions {
+	permissions := Permissions{}
+	for _, code := range p {
+		if allowed.Include(code) {
+			permissions = append(permissions, code)
+		}
+	}
+	return permissions
+}
+
+// Roles seeded by the migrations. Admins have every permission, editors can read and write
+// shortenings and viewers can only read them.
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// PermissionCodeRX matches permission codes of the form "resource:action".
+var PermissionCodeRX = regexp.MustCompile(`^[a-z0-9_-]+:[a-z0-9_-]+$`)
+
+func ValidatePermissionCode(v *validator.Validator, code string) {
+	v.Check(code != "", "code", "must be provided")
+	v.Check(len(code) <= 64, "code", "must not be more than 64 bytes long")
+	v.Check(validator.Matches(code, PermissionCodeRX), "code", `must have the form "resource:action" in lowercase`)
+}
@@ -1,61 +1,247 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/yantay0/url-shortener/internal/util"
+	"github.com/yantay0/url-shortener/internal/netguard"
+	"github.com/yantay0/url-shortener/internal/validator"
+	"golang.org/x/net/idna"
 )
 
-const alphabet = "ynAJfoSgdXHB5VasEMtcbPCr1uNZ4LG723ehWkvwYR6KpxjTm8iQUFqz9D"
+const (
+	alphabet = "ynAJfoSgdXHB5VasEMtcbPCr1uNZ4LG723ehWkvwYR6KpxjTm8iQUFqz9D"
+
+	// MaxURLLength is the maximum length of an original URL, most browsers and proxies don't
+	// handle much longer ones reliably.
+	MaxURLLength = 2048
+)
+
+const (
+	VerdictSafe    = "safe"
+	VerdictFlagged = "flagged"
+)
 
 var (
-	alphabetLen = uint32(len(alphabet))
+	// RedirectTypes holds the HTTP status codes a shortening is allowed to redirect with.
+	RedirectTypes = []int{
+		http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect,
+	}
 )
 
 type Shortening struct {
-	Identifier  string    `json:"identifier"`
-	OriginalURL string    `json:"original_url"`
-	Version     int32     `json:"version"` // The version number starts at 1 and is incremented each time the url information is updated.
-	UserID      int64     `json:"user_id"` // after adding seralization
-	Visits      int64     `json:"visits"`
-	CreatedAt   time.Time `json:"created_at"`
+	Identifier    string     `json:"identifier"`
+	Domain        string     `json:"domain,omitempty"` // Host of a custom domain, empty for the default one. Identifiers are unique per domain.
+	OriginalURL   string     `json:"original_url"`
+	Version       int32      `json:"version"` // The version number starts at 1 and is incremented each time the url information is updated.
+	UserID        int64      `json:"user_id"` // after adding seralization
+	Visits        int64      `json:"visits"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RedirectType  int        `json:"redirect_type,omitempty"` // Zero means the server-wide default redirect status code is used.
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	MaxVisits     *int64     `json:"max_visits,omitempty"`
+	ArchivedAt    *time.Time `json:"archived_at,omitempty"`    // Set by the background sweeper once the link has expired.
+	Password      password   `json:"-"`                        // Optional, protected links ask for it before redirecting.
+	URLHash       *string    `json:"-"`                        // Hash of the normalized original URL, only set for links created in dedupe mode.
+	SafetyVerdict string     `json:"safety_verdict,omitempty"` // VerdictSafe or VerdictFlagged, empty if the URL couldn't be checked.
+	SafetyReason  string     `json:"safety_reason,omitempty"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"` // Set by the destination health checker.
+	LastStatus    int        `json:"last_status,omitempty"`     // Zero if the destination couldn't be reached.
+	Healthy       *bool      `json:"healthy,omitempty"`         // Nil until the destination has been checked once.
+	CheckFailures int        `json:"-"`                         // Consecutive failed checks, used for the backoff.
+	NextCheckAt   *time.Time `json:"-"`
+	// Metadata of the destination page shown on the preview page, fetched when the link is created.
+	PreviewTitle       string `json:"preview_title,omitempty"`
+	PreviewDescription string `json:"preview_description,omitempty"`
+	PreviewImage       string `json:"preview_image,omitempty"`
+}
+
+// IsProtected reports whether the shortening requires a password before redirecting.
+func (s *Shortening) IsProtected() bool {
+	return s.Password.Hash != nil
 }
 
-// Generate a new unique ID for each shortening operation
-func GenerateShortening() string {
-	// Use uuid.New().String() to generate a unique ID
-	uniqueID := uuid.New().ID()
+// Expired reports whether the shortening has been archived, has passed its expiry time or
+// has used up its maximum number of visits.
+func (s *Shortening) Expired(now time.Time) bool {
+	switch {
+	case s.ArchivedAt != nil:
+		return true
+	case s.ExpiresAt != nil && !now.Before(*s.ExpiresAt):
+		return true
+	case s.MaxVisits != nil && s.Visits >= *s.MaxVisits:
+		return true
+	default:
+		return false
+	}
+}
 
-	var (
-		digits  []uint32
-		num     = uint32(uniqueID) // Convert the unique ID to a uint32 for processing
-		builder strings.Builder
-	)
+// PrependBaseURL returns the short URL of identifier below the path prefix of baseURL, if any.
+// Links on a custom domain replace the host of baseURL with the domain and keep its scheme, the
+// whole domain is dedicated to short links so the path prefix doesn't apply.
+func PrependBaseURL(baseURL, domain, identifier string) (string, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
 
-	for num > 0 {
-		digits = append(digits, num%alphabetLen)
-		num /= alphabetLen
+	if domain != "" {
+		parsed.Host = domain
+		parsed.Path = ""
 	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/") + "/" + identifier
+	parsed.RawPath = ""
+
+	return parsed.String(), nil
+}
 
-	util.Reverse(digits)
+// ValidateShortening checks the fields every write path sets on a shortening. A valid original
+// URL is replaced by its normalized form, with an internationalized host converted to punycode.
+func ValidateShortening(v *validator.Validator, shortening *Shortening) {
+	originalURL := strings.TrimSpace(shortening.OriginalURL)
 
-	for _, digit := range digits {
-		builder.WriteString(string(alphabet[digit]))
+	v.Check(originalURL != "", "original_url", "must be provided")
+	v.Check(len(originalURL) <= MaxURLLength, "original_url", fmt.Sprintf("must not be more than %d bytes long", MaxURLLength))
+
+	if originalURL != "" && len(originalURL) <= MaxURLLength {
+		normalized, err := normalizeOriginalURL(originalURL)
+		if err != nil {
+			v.AddError("original_url", err.Error())
+		} else {
+			shortening.OriginalURL = normalized
+		}
 	}
 
-	return builder.String()
+	ValidateRedirectType(v, shortening.RedirectType)
 }
 
-func PrependBaseURL(baseURL, identifier string) (string, error) {
-	parsed, err := url.Parse(baseURL)
+// normalizeOriginalURL checks that rawURL is an absolute http(s) URL without credentials and
+// returns it with a lowercase scheme and a punycode host. The errors are meant for the client.
+func normalizeOriginalURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", errors.New("must be a valid URL")
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	switch scheme {
+	case "http", "https":
+	case "javascript", "data", "vbscript", "file":
+		return "", fmt.Errorf("must not use the %s scheme", scheme)
+	default:
+		return "", errors.New("must be an absolute http or https URL")
+	}
+
+	if parsed.User != nil {
+		return "", errors.New("must not contain credentials")
+	}
+
+	hostname, port := parsed.Hostname(), parsed.Port()
+	if hostname == "" {
+		return "", errors.New("must contain a host")
+	}
+
+	// The server fetches destinations for previews and health checks, so they must not point
+	// into its own network. Host names are checked again after DNS resolution by netguard.
+	if ip := net.ParseIP(hostname); ip != nil {
+		if !netguard.Allowed(ip) {
+			return "", errors.New("must not point to a loopback, private or link-local address")
+		}
+	} else {
+		hostname, err = idna.Lookup.ToASCII(strings.TrimSuffix(hostname, "."))
+		if err != nil {
+			return "", errors.New("must contain a valid host name")
+		}
+		if hostname == "localhost" || strings.HasSuffix(hostname, ".localhost") {
+			return "", errors.New("must not point to a loopback, private or link-local address")
+		}
+	}
+
+	parsed.Scheme = scheme
+	switch {
+	case port != "":
+		parsed.Host = net.JoinHostPort(hostname, port)
+	case strings.Contains(hostname, ":"):
+		parsed.Host = "[" + hostname + "]" // IPv6 literal
+	default:
+		parsed.Host = hostname
+	}
+
+	return parsed.String(), nil
+}
+
+// NormalizeURL returns the canonical form of rawURL used to detect already shortened URLs: the
+// scheme and host are lowercased, default ports and trailing slashes are dropped and the query
+// parameters are sorted.
+func NormalizeURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
 	if err != nil {
 		return "", err
 	}
 
-	parsed.Path = identifier
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+
+	port := parsed.Port()
+	if (parsed.Scheme == "http" && port == "80") || (parsed.Scheme == "https" && port == "443") {
+		parsed.Host = strings.TrimSuffix(parsed.Host, ":"+port)
+	}
+
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	parsed.RawPath = strings.TrimRight(parsed.RawPath, "/")
+
+	// Encode sorts the parameters by key, values of the same key keep their order.
+	parsed.RawQuery = parsed.Query().Encode()
+	parsed.ForceQuery = false
 
 	return parsed.String(), nil
 }
+
+// HashURL returns the hex encoded SHA-256 hash of the normalized form of rawURL.
+func HashURL(rawURL string) (string, error) {
+	normalized, err := NormalizeURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// ValidateRedirectType checks that redirectType is either zero (server default) or one of the
+// supported redirect status codes.
+func ValidateRedirectType(v *validator.Validator, redirectType int) {
+	if redirectType == 0 {
+		return
+	}
+
+	for _, code := range RedirectTypes {
+		if redirectType == code {
+			return
+		}
+	}
+
+	v.AddError("redirect_type", "must be one of 301, 302, 307 or 308")
+}
+
+// ValidateExpiration checks the optional lifecycle fields of a shortening.
+func ValidateExpiration(v *validator.Validator, shortening *Shortening) {
+	if shortening.ExpiresAt != nil {
+		v.Check(shortening.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+
+	if shortening.MaxVisits != nil {
+		v.Check(*shortening.MaxVisits > 0, "max_visits", "must be greater than zero")
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import "testing"
+
+func TestNormalizeOriginalURLRejectsInternalHosts(t *testing.T) {
+	tests := []string{
+		"http://127.0.0.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1:8080/admin",
+		"http://192.168.1.1/",
+		"http://[::1]/",
+		"http://[fe80::1]/",
+		"http://0.0.0.0/",
+		"http://localhost:6060/debug/pprof",
+		"http://api.localhost/",
+	}
+
+	for _, rawURL := range tests {
+		if _, err := normalizeOriginalURL(rawURL); err == nil {
+			t.Errorf("normalizeOriginalURL(%q) accepted an internal host", rawURL)
+		}
+	}
+}
+
+func TestNormalizeOriginalURLAcceptsPublicHosts(t *testing.T) {
+	tests := map[string]string{
+		"HTTPS://Example.com/path":    "https://example.com/path",
+		"http://93.184.216.34/":       "http://93.184.216.34/",
+		"https://bücher.example/buch": "https://xn--bcher-kva.example/buch",
+	}
+
+	for rawURL, want := range tests {
+		got, err := normalizeOriginalURL(rawURL)
+		if err != nil {
+			t.Errorf("normalizeOriginalURL(%q) returned error %v", rawURL, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("normalizeOriginalURL(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
@@ -14,20 +14,32 @@ const (
 )
 
 type Token struct {
-	Plaintext string    `json:"token"`
-	Hash      []byte    `json:"-"`
-	UserID    int64     `json:"-"`
-	Expiry    time.Time `json:"expiry"`
-	Scope     string    `json:"-"`
+	ID          int64       `json:"id"`
+	Plaintext   string      `json:"token,omitempty"` // Only known right after the token is generated
+	Hash        []byte      `json:"-"`
+	UserID      int64       `json:"-"`
+	Name        string      `json:"name,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Expiry      *time.Time  `json:"expiry,omitempty"` // nil for API keys that don't expire
+	LastUsedAt  *time.Time  `json:"last_used_at,omitempty"`
+	UserAgent   string      `json:"user_agent,omitempty"` // User-Agent of the login that created a session token
+	Scope       string      `json:"-"`
+	Permissions Permissions `json:"permissions,omitempty"` // API keys are restricted to these permissions of their user
 }
 
+// GenerateToken returns a new random token of the user. A zero ttl creates a token that doesn't
+// expire.
 func GenerateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
 	token := &Token{
 		UserID: userID,
-		Expiry: time.Now().Add(ttl),
 		Scope:  scope,
 	}
 
+	if ttl > 0 {
+		expiry := time.Now().Add(ttl)
+		token.Expiry = &expiry
+	}
+
 	randomBytes := make([]byte, 16)
 
 	_, err := rand.Read(randomBytes)
@@ -47,3 +59,23 @@ func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
 	v.Check(tokenPlaintext != "", "token", "must be provided")
 	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
 }
+
+// ValidateAPIKey checks the name, expiry and permissions of a new API key. The permissions have to
+// be a subset of the permissions of the user creating the key.
+func ValidateAPIKey(v *validator.Validator, token *Token, userPermissions Permissions) {
+	v.Check(token.Name != "", "name", "must be provided")
+	v.Check(len(token.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	if token.Expiry != nil {
+		v.Check(token.Expiry.After(time.Now()), "expiry", "must be in the future")
+	}
+
+	v.Check(len(token.Permissions) != 0, "permissions", "must contain at least 1 permission")
+	v.Check(validator.Unique(token.Permissions), "permissions", "must not contain duplicate values")
+	for _, code := range token.Permissions {
+		if !userPermissions.Include(code) {
+			v.AddError("permissions", "must be a subset of your own permissions")
+			break
+		}
+	}
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/yantay0/url-shortener/internal/validator"
@@ -17,6 +18,7 @@ type User struct {
 	Email     string    `json:"email"`
 	Password  password  `json:"-"`
 	Activated bool      `json:"activated"`
+	Dedupe    bool      `json:"dedupe"` // Default of the dedupe option of new shortenings
 	Version   int       `json:"-"`
 }
 
@@ -58,6 +60,12 @@ func (p *password) Matches(plaintexPassword string) (bool, error) {
 	return true, nil
 }
 
+// NormalizeEmail returns the lowercase form emails are stored and looked up in, so they're
+// compared case-insensitively without relying on the collation of the database.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(email)
+}
+
 func ValidateEmail(v *validator.Validator, email string) {
 	v.Check(email != "", "email", "must be provided")
 	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
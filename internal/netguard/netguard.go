@@ -0,0 +1,101 @@
+// Package netguard provides an HTTP client for fetching user supplied URLs. The client refuses to
+// connect to loopback, private, carrier-grade NAT, link-local and unspecified addresses, so a
+// short link can't make the server request its own network.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// maxRedirects matches the limit of the default http.Client.
+const maxRedirects = 10
+
+var (
+	// forbiddenNets holds the ranges not covered by the net.IP methods: the "this network" block
+	// and the shared address space of carrier-grade NATs, which often reaches internal hosts.
+	forbiddenNets = []*net.IPNet{
+		mustParseCIDR("0.0.0.0/8"),
+		mustParseCIDR("100.64.0.0/10"),
+	}
+
+	// nat64 is the well-known NAT64 prefix, its last 32 bits are the IPv4 address connected to.
+	nat64 = mustParseCIDR("64:ff9b::/96")
+)
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+// Allowed reports whether ip is a publicly routable address. IPv4-mapped and NAT64 addresses are
+// judged by the IPv4 address they wrap.
+func Allowed(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if nat64.Contains(ip) {
+		ip = ip[net.IPv6len-net.IPv4len:]
+	}
+
+	for _, ipNet := range forbiddenNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// control runs after DNS resolution, right before connecting, so host names resolving to
+// internal addresses are rejected as well as IP literals.
+func control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !Allowed(ip) {
+		return fmt.Errorf("dial %s: %w", address, ErrForbiddenAddress)
+	}
+
+	return nil
+}
+
+// NewClient returns an HTTP client that only connects to public addresses. Every redirect hop is
+// dialed through the same check.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connections on our behalf and bypass the dialer check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
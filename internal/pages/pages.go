@@ -0,0 +1,26 @@
+package pages
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+)
+
+//go:embed "templates"
+var templateFS embed.FS
+
+// Render executes the "page" template defined in templateFile and returns the rendered HTML.
+func Render(templateFile string, data interface{}) ([]byte, error) {
+	tmpl, err := template.New("page").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	body := new(bytes.Buffer)
+	err = tmpl.ExecuteTemplate(body, "page", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return body.Bytes(), nil
+}
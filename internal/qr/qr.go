@@ -0,0 +1,140 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Levels maps the error correction levels accepted by Options to the ones of the encoder. Each
+// level can restore about 7%, 15%, 25% and 30% of a damaged code.
+var Levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options controls how a QR code is rendered.
+type Options struct {
+	Size       int    // Width and height of the image in pixels
+	Level      string // Error correction level, one of the keys of Levels
+	Margin     int    // Quiet zone around the code in modules
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// bitmap encodes content and returns its modules without the quiet zone, bitmap[y][x] is true
+// for a dark module.
+func bitmap(content string, opts Options) ([][]bool, error) {
+	level, found := Levels[opts.Level]
+	if !found {
+		return nil, fmt.Errorf("unknown error correction level %q", opts.Level)
+	}
+
+	q, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+
+	// The margin is drawn by the renderers, the encoder would always use four modules.
+	q.DisableBorder = true
+
+	return q.Bitmap(), nil
+}
+
+// PNG renders content as a PNG QR code. Every module is drawn with the same whole number of
+// pixels to keep the edges sharp, the pixels left over are added to the margin.
+func PNG(content string, opts Options) ([]byte, error) {
+	modules, err := bitmap(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(modules) + 2*opts.Margin
+	scale := max(opts.Size/total, 1)
+	size := max(opts.Size, scale*total)
+	offset := (size - scale*len(modules)) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{opts.Background, opts.Foreground})
+
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	err = png.Encode(buf, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SVG renders content as an SVG QR code, with one path covering all dark modules.
+func SVG(content string, opts Options) ([]byte, error) {
+	modules, err := bitmap(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(modules) + 2*opts.Margin
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(buf, `<rect width="%d" height="%d" fill="%s"/>`, total, total, hexColor(opts.Background))
+	fmt.Fprintf(buf, `<path fill="%s" d="`, hexColor(opts.Foreground))
+
+	for y, row := range modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(buf, "M%d %dh1v1h-1z", x+opts.Margin, y+opts.Margin)
+			}
+		}
+	}
+
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+// ParseColor parses a hex color in the RRGGBB or RGB form, with or without a leading '#'.
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+
+	if len(s) != 6 {
+		return color.RGBA{}, errors.New("must be a hex color like 000000 or fff")
+	}
+
+	rgb, err := hex.DecodeString(s)
+	if err != nil {
+		return color.RGBA{}, errors.New("must be a hex color like 000000 or fff")
+	}
+
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}, nil
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,115 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	qrreader "github.com/makiuchi-d/gozxing/qrcode"
+)
+
+var (
+	black = color.RGBA{A: 0xff}
+	white = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+const content = "http://localhost:4000/abcdefg"
+
+func TestPNGDecodes(t *testing.T) {
+	for _, level := range []string{"L", "M", "Q", "H"} {
+		for _, size := range []int{128, 256, 301} {
+			t.Run(fmt.Sprintf("%s/%d", level, size), func(t *testing.T) {
+				data, err := PNG(content, Options{Size: size, Level: level, Margin: 4, Foreground: black, Background: white})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				img, err := png.Decode(bytes.NewReader(data))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				bounds := img.Bounds()
+				if bounds.Dx() != size || bounds.Dy() != size {
+					t.Fatalf("got %dx%d image, want %dx%d", bounds.Dx(), bounds.Dy(), size, size)
+				}
+
+				bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				result, err := qrreader.NewQRCodeReader().Decode(bmp, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.GetText() != content {
+					t.Fatalf("decoded %q, want %q", result.GetText(), content)
+				}
+			})
+		}
+	}
+}
+
+func TestPNGGrowsTooSmallSize(t *testing.T) {
+	data, err := PNG(content, Options{Size: 10, Level: "M", Margin: 4, Foreground: black, Background: white})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every module needs at least one pixel.
+	if size := img.Bounds().Dx(); size <= 10 {
+		t.Fatalf("got %d pixels, want at least one per module", size)
+	}
+}
+
+func TestSVGSize(t *testing.T) {
+	data, err := SVG(content, Options{Size: 256, Level: "M", Margin: 2, Foreground: black, Background: white})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svg := string(data)
+	if !strings.Contains(svg, `width="256" height="256"`) {
+		t.Errorf("got %.120s, want a 256x256 image", svg)
+	}
+	if !strings.Contains(svg, `fill="#000000"`) || !strings.Contains(svg, `fill="#ffffff"`) {
+		t.Errorf("got %.200s, want the foreground and background colors", svg)
+	}
+}
+
+func TestUnknownLevel(t *testing.T) {
+	_, err := PNG(content, Options{Size: 256, Level: "X"})
+	if err == nil {
+		t.Fatal("got no error for an unknown error correction level")
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := map[string]color.RGBA{
+		"#ff8000": {R: 0xff, G: 0x80, A: 0xff},
+		"ff8000":  {R: 0xff, G: 0x80, A: 0xff},
+		"#fff":    white,
+	}
+	for s, want := range tests {
+		got, err := ParseColor(s)
+		if err != nil || got != want {
+			t.Errorf("got %v and error %v for %q, want %v", got, err, s, want)
+		}
+	}
+
+	for _, s := range []string{"", "#ff80", "zzzzzz"} {
+		if _, err := ParseColor(s); err == nil {
+			t.Errorf("got no error for %q", s)
+		}
+	}
+}
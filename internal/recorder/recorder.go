@@ -0,0 +1,140 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/lib/logger/jsonlog"
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+var (
+	ErrBufferFull = errors.New("click buffer is full")
+	ErrClosed     = errors.New("click recorder is closed")
+)
+
+// Store persists a batch of clicks.
+type Store interface {
+	InsertBatch(clicks []*model.Click) error
+}
+
+// Recorder takes click recording off the redirect hot path. Clicks are buffered in a channel
+// and a pool of workers writes them to the Store in batches, either once a batch reaches
+// batchSize clicks or every flushInterval, whichever comes first.
+type Recorder struct {
+	store         Store
+	logger        *jsonlog.Logger
+	clicks        chan *model.Click
+	workers       int
+	batchSize     int
+	flushInterval time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func New(store Store, logger *jsonlog.Logger, workers, bufferSize, batchSize int, flushInterval time.Duration) *Recorder {
+	return &Recorder{
+		store:         store,
+		logger:        logger,
+		clicks:        make(chan *model.Click, bufferSize),
+		workers:       workers,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+// Start launches the worker pool.
+func (r *Recorder) Start() {
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+}
+
+// Record queues a click without blocking. It returns ErrBufferFull if the workers can't keep
+// up, in which case the click is dropped.
+func (r *Recorder) Record(click *model.Click) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+
+	select {
+	case r.clicks <- click:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+// Shutdown stops accepting clicks and waits until the workers have flushed everything that is
+// still buffered, or until ctx is done.
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.clicks)
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Recorder) work() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*model.Click, 0, r.batchSize)
+
+	for {
+		select {
+		case click, ok := <-r.clicks:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+
+			batch = append(batch, click)
+			if len(batch) >= r.batchSize {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (r *Recorder) flush(batch []*model.Click) {
+	if len(batch) == 0 {
+		return
+	}
+
+	err := r.store.InsertBatch(batch)
+	if err != nil {
+		r.logger.PrintError(err, map[string]string{
+			"dropped_clicks": strconv.Itoa(len(batch)),
+		})
+	}
+}
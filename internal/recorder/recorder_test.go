@@ -0,0 +1,102 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/lib/logger/jsonlog"
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+// batches is a Store sending the size of every inserted batch.
+type batches chan int
+
+func (b batches) InsertBatch(clicks []*model.Click) error {
+	b <- len(clicks)
+	return nil
+}
+
+func newTestRecorder(store Store, batchSize int, flushInterval time.Duration) *Recorder {
+	return New(store, jsonlog.New(io.Discard, jsonlog.LevelOff), 1, 100, batchSize, flushInterval)
+}
+
+func record(t *testing.T, r *Recorder, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if err := r.Record(&model.Click{Identifier: "abc"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// nextBatch returns the size of the next inserted batch, or 0 if there is none within timeout.
+func nextBatch(store batches, timeout time.Duration) int {
+	select {
+	case n := <-store:
+		return n
+	case <-time.After(timeout):
+		return 0
+	}
+}
+
+func TestFlushOnBatchSize(t *testing.T) {
+	store := make(batches, 10)
+	r := newTestRecorder(store, 3, time.Hour)
+	r.Start()
+	defer r.Shutdown(context.Background())
+
+	record(t, r, 7)
+
+	for i := 0; i < 2; i++ {
+		if n := nextBatch(store, time.Second); n != 3 {
+			t.Fatalf("got batch of %d clicks, want 3", n)
+		}
+	}
+
+	// The remaining click waits for the next tick.
+	if n := nextBatch(store, 50*time.Millisecond); n != 0 {
+		t.Fatalf("got batch of %d clicks before the flush interval", n)
+	}
+}
+
+func TestFlushOnInterval(t *testing.T) {
+	store := make(batches, 10)
+	r := newTestRecorder(store, 100, 20*time.Millisecond)
+	r.Start()
+	defer r.Shutdown(context.Background())
+
+	record(t, r, 2)
+
+	if n := nextBatch(store, time.Second); n != 2 {
+		t.Fatalf("got batch of %d clicks, want 2 after the flush interval", n)
+	}
+}
+
+func TestShutdownDrainsBuffer(t *testing.T) {
+	store := make(batches, 10)
+	r := newTestRecorder(store, 100, time.Hour)
+
+	// Queue the clicks before the worker runs, so they are all still buffered on shutdown.
+	record(t, r, 5)
+	r.Start()
+
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shutdown returns after the flush, so the batch must be there already.
+	if len(store) != 1 {
+		t.Fatalf("got %d batches, want the buffered clicks flushed in one", len(store))
+	}
+	if n := <-store; n != 5 {
+		t.Fatalf("got batch of %d clicks, want the 5 buffered ones", n)
+	}
+
+	if err := r.Record(&model.Click{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("got error %v after Shutdown, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,148 @@
+package safety
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Blocklist flags URLs whose host is a blocked domain, or a subdomain of one, and URLs matching a
+// blocked regular expression. The rules are read from a file with one rule per line:
+//
+//	# phishing campaign 2024-03
+//	example.com
+//	regex:^https?://[^/]+/wp-admin/.*\.zip$
+//
+// Empty lines and lines starting with '#' are ignored.
+type Blocklist struct {
+	path string
+
+	mu       sync.RWMutex
+	domains  map[string]bool
+	patterns []*regexp.Regexp
+	modTime  time.Time
+}
+
+// NewBlocklist loads the blocklist from the file at path.
+func NewBlocklist(path string) (*Blocklist, error) {
+	b := &Blocklist{path: path}
+
+	err := b.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Reload reads the blocklist file again. The current rules are kept if the file is invalid.
+func (b *Blocklist) Reload() error {
+	file, err := os.Open(b.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	domains := make(map[string]bool)
+	var patterns []*regexp.Regexp
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		rule := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case rule == "" || strings.HasPrefix(rule, "#"):
+			continue
+		case strings.HasPrefix(rule, "regex:"):
+			rx, err := regexp.Compile(strings.TrimPrefix(rule, "regex:"))
+			if err != nil {
+				return fmt.Errorf("blocklist %s line %d: %w", b.path, line, err)
+			}
+			patterns = append(patterns, rx)
+		default:
+			domains[strings.TrimSuffix(strings.ToLower(rule), ".")] = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.domains = domains
+	b.patterns = patterns
+	b.modTime = info.ModTime()
+
+	return nil
+}
+
+// Watch reloads the blocklist whenever the modification time of the file changes. It checks the
+// file once every interval and never returns, so it's meant to run in its own goroutine.
+// Reload errors are passed to onError and the previous rules stay in place.
+func (b *Blocklist) Watch(interval time.Duration, onError func(error)) {
+	for {
+		time.Sleep(interval)
+
+		info, err := os.Stat(b.path)
+		if err != nil {
+			onError(err)
+			continue
+		}
+
+		b.mu.RLock()
+		changed := !info.ModTime().Equal(b.modTime)
+		b.mu.RUnlock()
+
+		if changed {
+			if err := b.Reload(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (b *Blocklist) Check(ctx context.Context, rawURL string) (Verdict, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Walk up from the full host name, so that blocking example.com also blocks
+	// login.example.com.
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	for host != "" {
+		if b.domains[host] {
+			return Verdict{Flagged: true, Reason: "blocklisted domain " + host}, nil
+		}
+
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+
+	for _, rx := range b.patterns {
+		if rx.MatchString(rawURL) {
+			return Verdict{Flagged: true, Reason: "blocklisted pattern " + rx.String()}, nil
+		}
+	}
+
+	return Verdict{}, nil
+}
@@ -0,0 +1,102 @@
+package safety
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeBlocklist(t *testing.T, path, rules string, modTime time.Time) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(rules), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Some file systems only keep seconds, an explicit time makes the change visible to Watch.
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func flagged(t *testing.T, b *Blocklist, rawURL string) bool {
+	t.Helper()
+
+	verdict, err := b.Check(context.Background(), rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return verdict.Flagged
+}
+
+func TestBlocklistRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "# comment\n\nevil.example\nregex:^https?://[^/]+/wp-admin/.*\\.zip$\n", time.Now())
+
+	b, err := NewBlocklist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"https://evil.example/":                 true,
+		"https://login.EVIL.example./account":   true,
+		"https://notevil.example/":              false,
+		"https://good.example/wp-admin/kit.zip": true,
+		"https://good.example/wp-admin/":        false,
+	}
+
+	for rawURL, want := range tests {
+		if got := flagged(t, b, rawURL); got != want {
+			t.Errorf("Check(%q) flagged = %v, want %v", rawURL, got, want)
+		}
+	}
+}
+
+func TestBlocklistReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	start := time.Now().Add(-time.Hour)
+	writeBlocklist(t, path, "old.example\n", start)
+
+	b, err := NewBlocklist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid file keeps the current rules.
+	writeBlocklist(t, path, "regex:(\n", start.Add(time.Minute))
+	if err := b.Reload(); err == nil {
+		t.Fatal("Reload accepted an invalid regex")
+	}
+	if !flagged(t, b, "https://old.example/") {
+		t.Fatal("invalid file replaced the previous rules")
+	}
+
+	errs := make(chan error, 10)
+	go b.Watch(10*time.Millisecond, func(err error) { errs <- err })
+
+	writeBlocklist(t, path, "new.example\n", start.Add(2*time.Minute))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !flagged(t, b, "https://new.example/") {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch didn't pick up the changed file")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if flagged(t, b, "https://old.example/") {
+		t.Error("removed rule is still applied after the reload")
+	}
+
+	select {
+	case err := <-errs:
+		t.Errorf("Watch reported error %v", err)
+	default:
+	}
+}
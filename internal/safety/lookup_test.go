@@ -0,0 +1,68 @@
+package safety
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubLookup stands in for a remote threat list.
+type stubLookup struct {
+	threats map[string]string
+	err     error
+}
+
+func (l stubLookup) Lookup(ctx context.Context, rawURL string) (string, error) {
+	return l.threats[rawURL], l.err
+}
+
+func TestLookupChecker(t *testing.T) {
+	checker := LookupChecker{Lookup: stubLookup{threats: map[string]string{"https://bad.example/": "MALWARE"}}}
+
+	verdict, err := checker.Check(context.Background(), "https://bad.example/")
+	if err != nil || !verdict.Flagged || verdict.Reason != "MALWARE" {
+		t.Fatalf("got verdict %+v and error %v, want flagged as MALWARE", verdict, err)
+	}
+
+	verdict, err = checker.Check(context.Background(), "https://good.example/")
+	if err != nil || verdict.Flagged {
+		t.Fatalf("got verdict %+v and error %v, want not flagged", verdict, err)
+	}
+
+	errUnavailable := errors.New("unavailable")
+	checker = LookupChecker{Lookup: stubLookup{err: errUnavailable}}
+
+	verdict, err = checker.Check(context.Background(), "https://bad.example/")
+	if !errors.Is(err, errUnavailable) || verdict.Flagged {
+		t.Fatalf("got verdict %+v and error %v, want the lookup error", verdict, err)
+	}
+}
+
+func TestSafeBrowsingSendsKeyInHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			t.Errorf("got query %q, the key must not be in the URL", r.URL.RawQuery)
+		}
+		if key := r.Header.Get("X-Goog-Api-Key"); key != "secret" {
+			t.Errorf("got X-Goog-Api-Key %q, want %q", key, "secret")
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"matches": []map[string]string{{"threatType": "SOCIAL_ENGINEERING"}},
+		})
+	}))
+	defer server.Close()
+
+	lookup := SafeBrowsing{APIKey: "secret", Client: server.Client(), Endpoint: server.URL}
+
+	threat, err := lookup.Lookup(context.Background(), "https://phish.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if threat != "SOCIAL_ENGINEERING" {
+		t.Fatalf("got threat %q, want SOCIAL_ENGINEERING", threat)
+	}
+}
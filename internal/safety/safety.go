@@ -0,0 +1,39 @@
+package safety
+
+import (
+	"context"
+	"errors"
+)
+
+// Verdict is the outcome of checking a URL.
+type Verdict struct {
+	Flagged bool
+	Reason  string // Why the URL was flagged, e.g. the matching blocklist entry or the threat type
+}
+
+// URLChecker checks whether a URL points to a malicious or phishing destination.
+type URLChecker interface {
+	Check(ctx context.Context, rawURL string) (Verdict, error)
+}
+
+// Chain runs its checkers in order and returns the first flagged verdict. A failing checker
+// doesn't stop the chain, its error is returned only if no other checker flagged the URL.
+type Chain []URLChecker
+
+func (c Chain) Check(ctx context.Context, rawURL string) (Verdict, error) {
+	var errs []error
+
+	for _, checker := range c {
+		verdict, err := checker.Check(ctx, rawURL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if verdict.Flagged {
+			return verdict, nil
+		}
+	}
+
+	return Verdict{}, errors.Join(errs...)
+}
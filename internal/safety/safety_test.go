@@ -0,0 +1,52 @@
+package safety
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubChecker returns a fixed verdict and error.
+type stubChecker struct {
+	verdict Verdict
+	err     error
+}
+
+func (c stubChecker) Check(ctx context.Context, rawURL string) (Verdict, error) {
+	return c.verdict, c.err
+}
+
+func TestChainFailsOpen(t *testing.T) {
+	errUnavailable := errors.New("provider unavailable")
+
+	chain := Chain{stubChecker{err: errUnavailable}, stubChecker{}}
+
+	verdict, err := chain.Check(context.Background(), "https://example.com/")
+	if verdict.Flagged {
+		t.Fatalf("got flagged verdict %+v, want the URL let through", verdict)
+	}
+	if !errors.Is(err, errUnavailable) {
+		t.Fatalf("got error %v, want the error of the failing checker", err)
+	}
+}
+
+func TestChainFlagsAfterFailingChecker(t *testing.T) {
+	flagged := Verdict{Flagged: true, Reason: "MALWARE"}
+
+	chain := Chain{stubChecker{err: errors.New("timeout")}, stubChecker{verdict: flagged}}
+
+	verdict, err := chain.Check(context.Background(), "https://example.com/")
+	if err != nil {
+		t.Fatalf("got error %v, want nil once a checker flagged the URL", err)
+	}
+	if verdict != flagged {
+		t.Fatalf("got verdict %+v, want %+v", verdict, flagged)
+	}
+}
+
+func TestEmptyChain(t *testing.T) {
+	verdict, err := Chain{}.Check(context.Background(), "https://example.com/")
+	if verdict.Flagged || err != nil {
+		t.Fatalf("got verdict %+v and error %v, want neither", verdict, err)
+	}
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/cache"
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+// CachedShorteningsStorage puts a cache in front of the GetOriginalUrl lookups of another
+// ShorteningsStorage. Update, Delete and RecordVerdict invalidate the cached entry. Shortenings
+// with max_visits aren't cached, the cached visits would let them redirect past their limit.
+type CachedShorteningsStorage struct {
+	ShorteningsStorage
+	Cache *cache.LRU[string, model.Shortening]
+}
+
+func NewCachedShorteningsStorage(next ShorteningsStorage, c *cache.LRU[string, model.Shortening]) *CachedShorteningsStorage {
+	return &CachedShorteningsStorage{ShorteningsStorage: next, Cache: c}
+}
+
+// cacheKey joins the domain and the identifier, neither of them can contain a '/'.
+func cacheKey(domain, identifier string) string {
+	return domain + "/" + identifier
+}
+
+func (s *CachedShorteningsStorage) GetOriginalUrl(domain, identifier string) (*model.Shortening, error) {
+	key := cacheKey(domain, identifier)
+
+	if shortening, found := s.Cache.Get(key); found {
+		if shortening.Expired(time.Now()) {
+			return nil, ErrLinkExpired
+		}
+		return &shortening, nil
+	}
+
+	shortening, err := s.ShorteningsStorage.GetOriginalUrl(domain, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if shortening.MaxVisits == nil {
+		s.Cache.Set(key, *shortening)
+	}
+
+	return shortening, nil
+}
+
+func (s *CachedShorteningsStorage) Update(shortening *model.Shortening) error {
+	err := s.ShorteningsStorage.Update(shortening)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Delete(cacheKey(shortening.Domain, shortening.Identifier))
+
+	return nil
+}
+
+func (s *CachedShorteningsStorage) Delete(domain, identifier string) error {
+	err := s.ShorteningsStorage.Delete(domain, identifier)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Delete(cacheKey(domain, identifier))
+
+	return nil
+}
+
+func (s *CachedShorteningsStorage) RecordVerdict(domain, identifier, verdict, reason string) error {
+	err := s.ShorteningsStorage.RecordVerdict(domain, identifier, verdict, reason)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Delete(cacheKey(domain, identifier))
+
+	return nil
+}
+
+// CachedPermissionsStorage puts a cache in front of the GetAllForUser lookups of another
+// PermissionsStorage. Grants and revokes invalidate the cached permissions of the user, a new
+// permission code those of every user since it's granted to the admin role. Other instances only
+// see the changes once their entries expire.
+type CachedPermissionsStorage struct {
+	PermissionsStorage
+	Cache *cache.LRU[int64, model.Permissions]
+}
+
+func NewCachedPermissionsStorage(next PermissionsStorage, c *cache.LRU[int64, model.Permissions]) *CachedPermissionsStorage {
+	return &CachedPermissionsStorage{PermissionsStorage: next, Cache: c}
+}
+
+func (s *CachedPermissionsStorage) GetAllForUser(userID int64) (model.Permissions, error) {
+	if permissions, found := s.Cache.Get(userID); found {
+		return permissions, nil
+	}
+
+	permissions, err := s.PermissionsStorage.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Cache.Set(userID, permissions)
+
+	return permissions, nil
+}
+
+func (s *CachedPermissionsStorage) AddForUser(userID int64, codes ...string) error {
+	err := s.PermissionsStorage.AddForUser(userID, codes...)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Delete(userID)
+
+	return nil
+}
+
+func (s *CachedPermissionsStorage) RemoveForUser(userID int64, codes ...string) error {
+	err := s.PermissionsStorage.RemoveForUser(userID, codes...)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Delete(userID)
+
+	return nil
+}
+
+func (s *CachedPermissionsStorage) AddRoleForUser(userID int64, role string) error {
+	err := s.PermissionsStorage.AddRoleForUser(userID, role)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Delete(userID)
+
+	return nil
+}
+
+func (s *CachedPermissionsStorage) RemoveRoleForUser(userID int64, role string) error {
+	err := s.PermissionsStorage.RemoveRoleForUser(userID, role)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Delete(userID)
+
+	return nil
+}
+
+func (s *CachedPermissionsStorage) Insert(code string) error {
+	err := s.PermissionsStorage.Insert(code)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Purge()
+
+	return nil
+}
+
+// CachedDomainsStorage puts a cache in front of the GetByHost lookups of another DomainsStorage,
+// which resolve the host of every redirect. Hosts without a verified domain, e.g. the default host
+// of the service, are cached as nil. Verifying and deleting a domain invalidate its host, deleting
+// also invalidates the cached shortenings of the domain, which are deleted with it. Other
+// instances only see the changes once their entries expire.
+type CachedDomainsStorage struct {
+	DomainsStorage
+	Cache            *cache.LRU[string, *model.Domain]
+	ShorteningsCache *cache.LRU[string, model.Shortening] // The cache of CachedShorteningsStorage
+}
+
+func NewCachedDomainsStorage(next DomainsStorage, c *cache.LRU[string, *model.Domain], shortenings *cache.LRU[string, model.Shortening]) *CachedDomainsStorage {
+	return &CachedDomainsStorage{DomainsStorage: next, Cache: c, ShorteningsCache: shortenings}
+}
+
+func (s *CachedDomainsStorage) GetByHost(host string) (*model.Domain, error) {
+	if domain, found := s.Cache.Get(host); found {
+		if domain == nil {
+			return nil, ErrRecordNotFound
+		}
+		domain := *domain
+		return &domain, nil
+	}
+
+	domain, err := s.DomainsStorage.GetByHost(host)
+	switch {
+	case errors.Is(err, ErrRecordNotFound):
+		s.Cache.Set(host, nil)
+		return nil, err
+	case err != nil:
+		return nil, err
+	}
+
+	cached := *domain
+	s.Cache.Set(host, &cached)
+
+	return domain, nil
+}
+
+func (s *CachedDomainsStorage) MarkVerified(domain *model.Domain) error {
+	err := s.DomainsStorage.MarkVerified(domain)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Delete(domain.Host)
+
+	return nil
+}
+
+func (s *CachedDomainsStorage) Delete(domain *model.Domain) error {
+	err := s.DomainsStorage.Delete(domain)
+	if err != nil {
+		return err
+	}
+
+	s.Cache.Delete(domain.Host)
+
+	prefix := cacheKey(domain.Host, "")
+	s.ShorteningsCache.DeleteFunc(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/cache"
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+	"github.com/yantay0/url-shortener/internal/storage/memory"
+)
+
+func TestCachedDomainsStorage(t *testing.T) {
+	store := memory.New()
+	domains := storage.NewCachedDomainsStorage(store.Domains, cache.New[string, *model.Domain](10, time.Hour), nil)
+
+	owner := &model.Domain{Host: "go.example.com", UserID: 1, VerificationToken: "a"}
+	claim := &model.Domain{Host: "go.example.com", UserID: 2, VerificationToken: "b"}
+	for _, domain := range []*model.Domain{owner, claim} {
+		if err := domains.Insert(domain); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The pending claims are cached as a host without a domain.
+	if _, err := domains.GetByHost("go.example.com"); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for an unverified host, want ErrRecordNotFound", err)
+	}
+
+	if err := domains.MarkVerified(owner); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := domains.GetByHost("go.example.com")
+	if err != nil || found.ID != owner.ID {
+		t.Fatalf("got domain %+v and error %v after verifying, want domain %d", found, err, owner.ID)
+	}
+
+	if err := domains.MarkVerified(claim); !errors.Is(err, storage.ErrDuplicateDomain) {
+		t.Fatalf("got error %v verifying a verified host again, want ErrDuplicateDomain", err)
+	}
+
+	if err := domains.Insert(&model.Domain{Host: "go.example.com", UserID: 3}); !errors.Is(err, storage.ErrDuplicateDomain) {
+		t.Fatalf("got error %v claiming a verified host, want ErrDuplicateDomain", err)
+	}
+
+	if err := domains.Delete(owner); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := domains.GetByHost("go.example.com"); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for a deleted domain, want ErrRecordNotFound", err)
+	}
+
+	// The other claim can be verified once the host is free again.
+	if err := domains.MarkVerified(claim); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeletingDomainInvalidatesShortenings(t *testing.T) {
+	store := memory.New()
+	shorteningsCache := cache.New[string, model.Shortening](10, time.Hour)
+	shortenings := storage.NewCachedShorteningsStorage(store.Shortenings, shorteningsCache)
+	domains := storage.NewCachedDomainsStorage(store.Domains, cache.New[string, *model.Domain](10, time.Hour), shorteningsCache)
+
+	domain := &model.Domain{Host: "go.example.com", UserID: 1, VerificationToken: "a"}
+	if err := domains.Insert(domain); err != nil {
+		t.Fatal(err)
+	}
+	if err := domains.MarkVerified(domain); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, host := range []string{"", "go.example.com"} {
+		shortening := &model.Shortening{Identifier: "abc", Domain: host, OriginalURL: "https://example.com/", UserID: 1}
+		if err := shortenings.SaveUserShortening(shortening); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := shortenings.GetOriginalUrl(host, "abc"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := domains.Delete(domain); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := shortenings.GetOriginalUrl("go.example.com", "abc"); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for a shortening of the deleted domain, want ErrRecordNotFound", err)
+	}
+	if _, found := shorteningsCache.Get("/abc"); !found {
+		t.Error("shortening of the default domain evicted, want it kept")
+	}
+}
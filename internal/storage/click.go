@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+type ClicksStorage interface {
+	// InsertBatch stores the clicks and increments the visits of the clicked shortenings, except
+	// for the clicks already counted on redirect.
+	InsertBatch(clicks []*model.Click) error
+	// Stats returns the clicks of a shortening in [from, to) bucketed by interval, which must be
+	// one of model.StatsIntervals.
+	Stats(domain, identifier, interval string, from, to time.Time) (*model.ClickStats, error)
+}
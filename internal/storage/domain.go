@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+var (
+	ErrDuplicateDomain = errors.New("duplicate domain")
+)
+
+type DomainsStorage interface {
+	// Insert returns ErrDuplicateDomain if the host is already verified by any user or already
+	// claimed by the same user. Other users' pending claims don't block it.
+	Insert(domain *model.Domain) error
+	Get(id int64) (*model.Domain, error)
+	// GetByHost returns the verified domain of the host, pending claims aren't found.
+	GetByHost(host string) (*model.Domain, error)
+	GetAllForUser(userID int64) ([]*model.Domain, error)
+	// MarkVerified sets the verification time of the domain. It returns ErrDuplicateDomain if
+	// another user has verified the host in the meantime.
+	MarkVerified(domain *model.Domain) error
+	// Delete removes the domain. The shortenings on a verified domain are deleted with it, they
+	// couldn't be resolved anymore.
+	Delete(domain *model.Domain) error
+}
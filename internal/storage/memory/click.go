@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+type ClicksStorage struct {
+	db *db
+}
+
+func (s *ClicksStorage) InsertBatch(clicks []*model.Click) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, click := range clicks {
+		key := shorteningKey{click.Domain, click.Identifier}
+
+		shortening, found := s.db.shortenings[key]
+		if !found {
+			continue
+		}
+
+		if !click.Counted {
+			shortening.Visits++
+			s.db.shortenings[key] = shortening
+		}
+
+		s.db.nextClickID++
+		click.ID = s.db.nextClickID
+		s.db.clicks = append(s.db.clicks, *click)
+	}
+
+	return nil
+}
+
+func (s *ClicksStorage) Stats(domain, identifier, interval string, from, to time.Time) (*model.ClickStats, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	stats := &model.ClickStats{
+		Interval: interval,
+		From:     from,
+		To:       to,
+		Buckets:  []*model.ClickBucket{},
+	}
+
+	buckets := make(map[time.Time]int64)
+	referrers := make(map[string]int64)
+	uaFamilies := make(map[string]int64)
+	devices := make(map[string]int64)
+
+	for _, click := range s.db.clicks {
+		if click.Domain != domain || click.Identifier != identifier || click.ClickedAt.Before(from) || !click.ClickedAt.Before(to) {
+			continue
+		}
+
+		stats.Total++
+		buckets[truncate(click.ClickedAt, interval)]++
+		referrers[click.Referrer]++
+		uaFamilies[click.UAFamily]++
+		devices[click.Device]++
+	}
+
+	for start, clicks := range buckets {
+		stats.Buckets = append(stats.Buckets, &model.ClickBucket{Start: start, Clicks: clicks})
+	}
+	sort.Slice(stats.Buckets, func(i, j int) bool {
+		return stats.Buckets[i].Start.Before(stats.Buckets[j].Start)
+	})
+
+	stats.Referrers = topCounts(referrers)
+	stats.UAFamilies = topCounts(uaFamilies)
+	stats.Devices = topCounts(devices)
+
+	return stats, nil
+}
+
+// truncate behaves like date_trunc in UTC, weeks start on Monday.
+func truncate(t time.Time, interval string) time.Time {
+	t = t.UTC()
+
+	switch interval {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// topCounts returns the ten most frequent values, like the breakdown queries of Postgres.
+func topCounts(counts map[string]int64) []*model.ClickCount {
+	result := []*model.ClickCount{}
+	for value, clicks := range counts {
+		result = append(result, &model.ClickCount{Value: value, Clicks: clicks})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Clicks == result[j].Clicks {
+			return result[i].Value < result[j].Value
+		}
+		return result[i].Clicks > result[j].Clicks
+	})
+
+	if len(result) > 10 {
+		result = result[:10]
+	}
+
+	return result
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type DomainsStorage struct {
+	db *db
+}
+
+func (s *DomainsStorage) Insert(domain *model.Domain) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, other := range s.db.domains {
+		if other.Host == domain.Host && (other.IsVerified() || other.UserID == domain.UserID) {
+			return storage.ErrDuplicateDomain
+		}
+	}
+
+	s.db.nextDomainID++
+	domain.ID = s.db.nextDomainID
+	domain.CreatedAt = time.Now().Truncate(time.Second)
+
+	s.db.domains[domain.ID] = *domain
+
+	return nil
+}
+
+func (s *DomainsStorage) Get(id int64) (*model.Domain, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	domain, found := s.db.domains[id]
+	if !found {
+		return nil, storage.ErrRecordNotFound
+	}
+
+	return &domain, nil
+}
+
+func (s *DomainsStorage) GetByHost(host string) (*model.Domain, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, domain := range s.db.domains {
+		if domain.Host == host && domain.IsVerified() {
+			return &domain, nil
+		}
+	}
+
+	return nil, storage.ErrRecordNotFound
+}
+
+func (s *DomainsStorage) GetAllForUser(userID int64) ([]*model.Domain, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	domains := []*model.Domain{}
+	for _, domain := range s.db.domains {
+		if domain.UserID == userID {
+			domain := domain
+			domains = append(domains, &domain)
+		}
+	}
+
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Host < domains[j].Host
+	})
+
+	return domains, nil
+}
+
+func (s *DomainsStorage) MarkVerified(domain *model.Domain) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	current, found := s.db.domains[domain.ID]
+	if !found {
+		return storage.ErrRecordNotFound
+	}
+
+	for _, other := range s.db.domains {
+		if other.Host == current.Host && other.ID != current.ID && other.IsVerified() {
+			return storage.ErrDuplicateDomain
+		}
+	}
+
+	now := time.Now().Truncate(time.Second)
+	current.VerifiedAt = &now
+	s.db.domains[current.ID] = current
+
+	domain.VerifiedAt = &now
+
+	return nil
+}
+
+func (s *DomainsStorage) Delete(domain *model.Domain) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	current, found := s.db.domains[domain.ID]
+	if !found {
+		return storage.ErrRecordNotFound
+	}
+
+	delete(s.db.domains, current.ID)
+
+	// Pending claims of a host don't own any shortenings on it, the verified domain does.
+	if current.IsVerified() {
+		for key := range s.db.shortenings {
+			if key.domain == current.Host {
+				delete(s.db.shortenings, key)
+			}
+		}
+
+		clicks := s.db.clicks[:0]
+		for _, click := range s.db.clicks {
+			if click.Domain != current.Host {
+				clicks = append(clicks, click)
+			}
+		}
+		s.db.clicks = clicks
+	}
+
+	return nil
+}
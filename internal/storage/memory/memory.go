@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+// db is the shared state of the in-memory repositories. A single mutex guards all tables,
+// which keeps cross-table operations (e.g. clicks incrementing visits) consistent.
+type db struct {
+	mu sync.RWMutex
+
+	users            map[int64]model.User
+	nextUserID       int64
+	tokens           map[string]model.Token // keyed by the token hash
+	nextTokenID      int64
+	permissions      []string
+	usersPermissions map[int64]map[string]bool
+	roles            map[string][]string // permission codes of each role
+	usersRoles       map[int64]map[string]bool
+	shortenings      map[shorteningKey]model.Shortening
+	nextShorteningID int64
+	clicks           []model.Click
+	nextClickID      int64
+	domains          map[int64]model.Domain
+	nextDomainID     int64
+}
+
+// shorteningKey is the primary key of a shortening, identifiers are unique per domain.
+type shorteningKey struct {
+	domain     string
+	identifier string
+}
+
+func keyOf(shortening *model.Shortening) shorteningKey {
+	return shorteningKey{shortening.Domain, shortening.Identifier}
+}
+
+// New returns an in-memory implementation of every repository. Nothing is persisted, which
+// makes it suitable for development and handler tests that shouldn't need a database.
+func New() storage.Storage {
+	d := &db{
+		users:            make(map[int64]model.User),
+		tokens:           make(map[string]model.Token),
+		usersPermissions: make(map[int64]map[string]bool),
+		usersRoles:       make(map[int64]map[string]bool),
+		shortenings:      make(map[shorteningKey]model.Shortening),
+		domains:          make(map[int64]model.Domain),
+		// Same start as shortening_identifier_seq.
+		nextShorteningID: 999_999,
+		// Same as the permissions and roles seeded by the migrations.
+		permissions: []string{"shortenings:read", "shortenings:write", "shortenings:admin", "permissions:read", "permissions:write"},
+		roles: map[string][]string{
+			model.RoleAdmin:  {"shortenings:read", "shortenings:write", "shortenings:admin", "permissions:read", "permissions:write"},
+			model.RoleEditor: {"shortenings:read", "shortenings:write"},
+			model.RoleViewer: {"shortenings:read"},
+		},
+	}
+
+	return storage.Storage{
+		Shortenings: &ShorteningsStorage{db: d},
+		Permissions: &PermissionsStorage{db: d},
+		Tokens:      &TokenStorage{db: d},
+		Users:       &UserStorage{db: d},
+		Clicks:      &ClicksStorage{db: d},
+		Domains:     &DomainsStorage{db: d},
+	}
+}
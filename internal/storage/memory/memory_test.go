@@ -0,0 +1,11 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/yantay0/url-shortener/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, New())
+}
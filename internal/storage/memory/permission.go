@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type PermissionsStorage struct {
+	db *db
+}
+
+func (s *PermissionsStorage) GetAllForUser(userID int64) (model.Permissions, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	codes := make(map[string]bool)
+	for code := range s.db.usersPermissions[userID] {
+		codes[code] = true
+	}
+	for role := range s.db.usersRoles[userID] {
+		for _, code := range s.db.roles[role] {
+			codes[code] = true
+		}
+	}
+
+	permissions := model.Permissions{}
+	for code := range codes {
+		permissions = append(permissions, code)
+	}
+	sort.Strings(permissions)
+
+	return permissions, nil
+}
+
+// AddForUser grants the codes that exist, unknown codes are ignored like in the INSERT ... SELECT
+// of the Postgres implementation.
+func (s *PermissionsStorage) AddForUser(userID int64, codes ...string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.usersPermissions[userID] == nil {
+		s.db.usersPermissions[userID] = make(map[string]bool)
+	}
+
+	for _, code := range codes {
+		for _, permission := range s.db.permissions {
+			if code == permission {
+				s.db.usersPermissions[userID][code] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *PermissionsStorage) RemoveForUser(userID int64, codes ...string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, code := range codes {
+		delete(s.db.usersPermissions[userID], code)
+	}
+
+	return nil
+}
+
+func (s *PermissionsStorage) GetRolesForUser(userID int64) ([]string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	roles := []string{}
+	for role := range s.db.usersRoles[userID] {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	return roles, nil
+}
+
+func (s *PermissionsStorage) AddRoleForUser(userID int64, role string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.roles[role]; !ok {
+		return storage.ErrRecordNotFound
+	}
+
+	if s.db.usersRoles[userID] == nil {
+		s.db.usersRoles[userID] = make(map[string]bool)
+	}
+	s.db.usersRoles[userID][role] = true
+
+	return nil
+}
+
+func (s *PermissionsStorage) RemoveRoleForUser(userID int64, role string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.roles[role]; !ok {
+		return storage.ErrRecordNotFound
+	}
+
+	if !s.db.usersRoles[userID][role] {
+		return storage.ErrRoleNotGranted
+	}
+	delete(s.db.usersRoles[userID], role)
+
+	return nil
+}
+
+func (s *PermissionsStorage) GetAll() (model.Permissions, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	permissions := append(model.Permissions(nil), s.db.permissions...)
+	sort.Strings(permissions)
+
+	return permissions, nil
+}
+
+func (s *PermissionsStorage) Insert(code string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, permission := range s.db.permissions {
+		if code == permission {
+			return storage.ErrDuplicatePermission
+		}
+	}
+
+	s.db.permissions = append(s.db.permissions, code)
+	s.db.roles[model.RoleAdmin] = append(s.db.roles[model.RoleAdmin], code)
+
+	return nil
+}
@@ -0,0 +1,380 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type ShorteningsStorage struct {
+	db *db
+}
+
+func (s *ShorteningsStorage) Insert(shortening *model.Shortening) error {
+	return s.SaveUserShortening(shortening)
+}
+
+func (s *ShorteningsStorage) Get(domain, identifier string) (*model.Shortening, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	shortening, found := s.db.shortenings[shorteningKey{domain, identifier}]
+	if !found {
+		return nil, storage.ErrRecordNotFound
+	}
+
+	return &shortening, nil
+}
+
+func (s *ShorteningsStorage) Update(shortening *model.Shortening) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	current, found := s.db.shortenings[keyOf(shortening)]
+	if !found || current.Version != shortening.Version {
+		return storage.ErrEditConflict
+	}
+
+	if s.urlHashTaken(shortening) {
+		return storage.ErrDuplicateURL
+	}
+
+	// A new destination is health checked again soon.
+	if current.OriginalURL != shortening.OriginalURL {
+		current.NextCheckAt = nil
+	}
+
+	// A new expiry or visit limit unarchives the link.
+	if !sameTime(current.ExpiresAt, shortening.ExpiresAt) || !sameInt(current.MaxVisits, shortening.MaxVisits) {
+		current.ArchivedAt = nil
+	}
+
+	// Only the editable fields are taken over, like in the UPDATE statement.
+	current.OriginalURL = shortening.OriginalURL
+	current.RedirectType = shortening.RedirectType
+	current.ExpiresAt = shortening.ExpiresAt
+	current.MaxVisits = shortening.MaxVisits
+	current.Password = shortening.Password
+	current.URLHash = shortening.URLHash
+	current.SafetyVerdict = shortening.SafetyVerdict
+	current.SafetyReason = shortening.SafetyReason
+	current.Version++
+
+	s.db.shortenings[keyOf(&current)] = current
+
+	shortening.Version = current.Version
+	shortening.ArchivedAt = current.ArchivedAt
+
+	return nil
+}
+
+// sameTime reports whether a and b are both nil or the same instant.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func sameInt(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s *ShorteningsStorage) Delete(domain, identifier string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := shorteningKey{domain, identifier}
+	if _, found := s.db.shortenings[key]; !found {
+		return storage.ErrRecordNotFound
+	}
+
+	delete(s.db.shortenings, key)
+
+	// Same as ON DELETE CASCADE on clicks.
+	clicks := s.db.clicks[:0]
+	for _, click := range s.db.clicks {
+		if click.Domain != domain || click.Identifier != identifier {
+			clicks = append(clicks, click)
+		}
+	}
+	s.db.clicks = clicks
+
+	return nil
+}
+
+func (s *ShorteningsStorage) GetAll(originalURL string, healthy *bool, userID int64, filters model.Filters) ([]*model.Shortening, model.Metadata, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	matches := []*model.Shortening{}
+	for _, shortening := range s.db.shortenings {
+		if healthy != nil && (shortening.Healthy == nil || *shortening.Healthy != *healthy) {
+			continue
+		}
+
+		if userID != 0 && shortening.UserID != userID {
+			continue
+		}
+
+		if originalURL == "" || strings.EqualFold(shortening.OriginalURL, originalURL) {
+			shortening := shortening
+			matches = append(matches, &shortening)
+		}
+	}
+
+	column, descending := filters.SortColumn(), filters.SortDirection() == "DESC"
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := sortValue(matches[i], column), sortValue(matches[j], column)
+		if a == b {
+			return less(matches[i], matches[j])
+		}
+		if descending {
+			return a > b
+		}
+		return a < b
+	})
+
+	totalRecords := len(matches)
+
+	start := min(filters.Offset(), totalRecords)
+	end := min(start+filters.Limit(), totalRecords)
+
+	metadata := model.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return matches[start:end], metadata, nil
+}
+
+// less orders shortenings by identifier and domain, like the tie breaker of the SQL queries.
+func less(a, b *model.Shortening) bool {
+	if a.Identifier == b.Identifier {
+		return a.Domain < b.Domain
+	}
+	return a.Identifier < b.Identifier
+}
+
+func sortValue(shortening *model.Shortening, column string) string {
+	switch column {
+	case "original_url":
+		return shortening.OriginalURL
+	default:
+		return shortening.Identifier
+	}
+}
+
+func (s *ShorteningsStorage) GetUserAllShortenings(userID int64) ([]*model.Shortening, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	shortenings := []*model.Shortening{}
+	for _, shortening := range s.db.shortenings {
+		if shortening.UserID == userID {
+			shortening := shortening
+			shortenings = append(shortenings, &shortening)
+		}
+	}
+
+	sort.Slice(shortenings, func(i, j int) bool {
+		return less(shortenings[i], shortenings[j])
+	})
+
+	return shortenings, nil
+}
+
+func (s *ShorteningsStorage) SaveUserShortening(shortening *model.Shortening) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.urlHashTaken(shortening) {
+		return storage.ErrDuplicateURL
+	}
+
+	if _, found := s.db.shortenings[keyOf(shortening)]; found {
+		return storage.ErrIdentifierExists
+	}
+
+	shortening.CreatedAt = time.Now().Truncate(time.Second)
+	shortening.Version = 1
+
+	s.db.shortenings[keyOf(shortening)] = *shortening
+
+	return nil
+}
+
+func (s *ShorteningsStorage) GetByURLHash(userID int64, domain, urlHash string) (*model.Shortening, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, shortening := range s.db.shortenings {
+		if shortening.UserID == userID && shortening.Domain == domain && shortening.URLHash != nil && *shortening.URLHash == urlHash {
+			return &shortening, nil
+		}
+	}
+
+	return nil, storage.ErrRecordNotFound
+}
+
+// urlHashTaken reports whether another shortening of the same user on the same domain already
+// has the URLHash of shortening, like the unique index on (user_id, domain, url_hash). The caller
+// must hold the lock.
+func (s *ShorteningsStorage) urlHashTaken(shortening *model.Shortening) bool {
+	if shortening.URLHash == nil {
+		return false
+	}
+
+	for _, other := range s.db.shortenings {
+		if keyOf(&other) != keyOf(shortening) && other.UserID == shortening.UserID && other.Domain == shortening.Domain &&
+			other.URLHash != nil && *other.URLHash == *shortening.URLHash {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *ShorteningsStorage) GetOriginalUrl(domain, identifier string) (*model.Shortening, error) {
+	shortening, err := s.Get(domain, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if shortening.Expired(time.Now()) {
+		return nil, storage.ErrLinkExpired
+	}
+
+	return shortening, nil
+}
+
+func (s *ShorteningsStorage) CountVisit(domain, identifier string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := shorteningKey{domain, identifier}
+	shortening, found := s.db.shortenings[key]
+	if !found || (shortening.MaxVisits != nil && shortening.Visits >= *shortening.MaxVisits) {
+		return storage.ErrLinkExpired
+	}
+
+	shortening.Visits++
+	s.db.shortenings[key] = shortening
+
+	return nil
+}
+
+func (s *ShorteningsStorage) RecordVerdict(domain, identifier, verdict, reason string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := shorteningKey{domain, identifier}
+	shortening, found := s.db.shortenings[key]
+	if !found {
+		return storage.ErrRecordNotFound
+	}
+
+	shortening.SafetyVerdict = verdict
+	shortening.SafetyReason = reason
+	s.db.shortenings[key] = shortening
+
+	return nil
+}
+
+func (s *ShorteningsStorage) GetDueForHealthCheck(limit int) ([]*model.Shortening, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	now := time.Now()
+
+	due := []*model.Shortening{}
+	for _, shortening := range s.db.shortenings {
+		if shortening.ArchivedAt == nil && (shortening.NextCheckAt == nil || !now.Before(*shortening.NextCheckAt)) {
+			shortening := shortening
+			due = append(due, &shortening)
+		}
+	}
+
+	// Never checked first, like NULLS FIRST.
+	sort.Slice(due, func(i, j int) bool {
+		a, b := due[i].NextCheckAt, due[j].NextCheckAt
+		switch {
+		case a == nil && b == nil:
+			return less(due[i], due[j])
+		case a == nil || b == nil:
+			return a == nil
+		case a.Equal(*b):
+			return less(due[i], due[j])
+		default:
+			return a.Before(*b)
+		}
+	})
+
+	return due[:min(limit, len(due))], nil
+}
+
+func (s *ShorteningsStorage) RecordHealth(shortening *model.Shortening) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	current, found := s.db.shortenings[keyOf(shortening)]
+	if !found {
+		return nil
+	}
+
+	current.LastCheckedAt = shortening.LastCheckedAt
+	current.LastStatus = shortening.LastStatus
+	current.Healthy = shortening.Healthy
+	current.CheckFailures = shortening.CheckFailures
+	current.NextCheckAt = shortening.NextCheckAt
+	s.db.shortenings[keyOf(&current)] = current
+
+	return nil
+}
+
+func (s *ShorteningsStorage) RecordPreview(shortening *model.Shortening) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	current, found := s.db.shortenings[keyOf(shortening)]
+	if !found {
+		return nil
+	}
+
+	current.PreviewTitle = shortening.PreviewTitle
+	current.PreviewDescription = shortening.PreviewDescription
+	current.PreviewImage = shortening.PreviewImage
+	s.db.shortenings[keyOf(&current)] = current
+
+	return nil
+}
+
+func (s *ShorteningsStorage) ArchiveExpired() (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := time.Now()
+
+	var archived int64
+	for key, shortening := range s.db.shortenings {
+		if shortening.ArchivedAt == nil && shortening.Expired(now) {
+			shortening.ArchivedAt = &now
+			s.db.shortenings[key] = shortening
+			archived++
+		}
+	}
+
+	return archived, nil
+}
+
+func (s *ShorteningsStorage) NextID() (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.nextShorteningID++
+
+	return s.db.nextShorteningID, nil
+}
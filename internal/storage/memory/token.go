@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"sort"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type TokenStorage struct {
+	db *db
+}
+
+func (s *TokenStorage) New(userID int64, ttl time.Duration, scope string) (*model.Token, error) {
+	token, err := model.GenerateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Insert(token)
+	return token, err
+}
+
+func (s *TokenStorage) Insert(token *model.Token) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.nextTokenID++
+	token.ID = s.db.nextTokenID
+	token.CreatedAt = time.Now()
+
+	// Unknown codes are dropped like in the INSERT ... SELECT of the Postgres implementation.
+	permissions := model.Permissions{}
+	for _, code := range s.db.permissions {
+		if token.Permissions.Include(code) {
+			permissions = append(permissions, code)
+		}
+	}
+	sort.Strings(permissions)
+
+	stored := *token
+	stored.Plaintext = ""
+	stored.Permissions = permissions
+	s.db.tokens[string(token.Hash)] = stored
+
+	return nil
+}
+
+func (s *TokenStorage) DeleteAllForUser(scope string, userID int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for hash, token := range s.db.tokens {
+		if token.Scope == scope && token.UserID == userID {
+			delete(s.db.tokens, hash)
+		}
+	}
+
+	return nil
+}
+
+func (s *TokenStorage) GetForPlaintext(scope, tokenPlaintext string) (*model.Token, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	token, found := s.db.tokens[string(tokenHash[:])]
+	if !found || token.Scope != scope || expired(token, time.Now()) {
+		return nil, storage.ErrRecordNotFound
+	}
+
+	return &token, nil
+}
+
+func (s *TokenStorage) GetAllForUser(scope string, userID int64) ([]*model.Token, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	now := time.Now()
+
+	tokens := []*model.Token{}
+	for _, token := range s.db.tokens {
+		if token.Scope == scope && token.UserID == userID && !expired(token, now) {
+			token := token
+			tokens = append(tokens, &token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID < tokens[j].ID
+	})
+
+	return tokens, nil
+}
+
+func (s *TokenStorage) DeleteForUser(scope string, id, userID int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for hash, token := range s.db.tokens {
+		if token.Scope == scope && token.ID == id && token.UserID == userID {
+			delete(s.db.tokens, hash)
+			return nil
+		}
+	}
+
+	return storage.ErrRecordNotFound
+}
+
+func (s *TokenStorage) MarkUsed(token *model.Token) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, found := s.db.tokens[string(token.Hash)]
+	if !found {
+		return storage.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	stored.LastUsedAt = &now
+	s.db.tokens[string(token.Hash)] = stored
+	token.LastUsedAt = &now
+
+	return nil
+}
+
+// expired reports whether a token with an expiry has passed it.
+func expired(token model.Token, now time.Time) bool {
+	return token.Expiry != nil && !token.Expiry.After(now)
+}
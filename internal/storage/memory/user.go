@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type UserStorage struct {
+	db *db
+}
+
+// emailTaken mirrors the uniqueness of users.email. Emails are stored lowercase, so they're
+// compared exactly.
+func (s *UserStorage) emailTaken(email string, exceptID int64) bool {
+	for _, user := range s.db.users {
+		if user.ID != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *UserStorage) Insert(user *model.User) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.emailTaken(user.Email, 0) {
+		return storage.ErrDuplicateEmail
+	}
+
+	s.db.nextUserID++
+	user.ID = s.db.nextUserID
+	user.CreatedAt = time.Now().Truncate(time.Second)
+	user.Version = 1
+
+	s.db.users[user.ID] = *user
+
+	return nil
+}
+
+func (s *UserStorage) GetByEmail(email string) (*model.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, user := range s.db.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	return nil, storage.ErrRecordNotFound
+}
+
+func (s *UserStorage) GetByID(id int64) (*model.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	user, found := s.db.users[id]
+	if !found {
+		return nil, storage.ErrRecordNotFound
+	}
+
+	return &user, nil
+}
+
+func (s *UserStorage) Update(user *model.User) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.emailTaken(user.Email, user.ID) {
+		return storage.ErrDuplicateEmail
+	}
+
+	current, found := s.db.users[user.ID]
+	if !found || current.Version != user.Version {
+		return storage.ErrEditConflict
+	}
+
+	user.Version++
+	s.db.users[user.ID] = *user
+
+	return nil
+}
+
+func (s *UserStorage) GetForToken(tokenScope, tokenPlaintext string) (*model.User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	token, found := s.db.tokens[string(tokenHash[:])]
+	if !found || token.Scope != tokenScope || expired(token, time.Now()) {
+		return nil, storage.ErrRecordNotFound
+	}
+
+	user, found := s.db.users[token.UserID]
+	if !found {
+		return nil, storage.ErrRecordNotFound
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// advisoryLockID is an arbitrary key shared by every instance of the service.
+const advisoryLockID = 7307413960274913112
+
+type dialect interface {
+	createTable(ctx context.Context, conn *sql.Conn) error
+	lock(ctx context.Context, conn *sql.Conn) error
+	unlock(ctx context.Context, conn *sql.Conn) error
+	forceUnlock(ctx context.Context, conn *sql.Conn) error
+}
+
+var dialects = map[string]dialect{
+	"postgres": postgresDialect{},
+	"sqlite":   sqliteDialect{},
+}
+
+// postgresDialect serializes migrations with a session level advisory lock. Concurrent deploys
+// wait for the lock and then find nothing left to apply.
+type postgresDialect struct{}
+
+func (postgresDialect) createTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint NOT NULL PRIMARY KEY,
+			dirty boolean NOT NULL
+		)`)
+	return err
+}
+
+func (postgresDialect) lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockID)
+	return err
+}
+
+func (postgresDialect) unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryLockID)
+	return err
+}
+
+// forceUnlock is a no-op, advisory locks are released when the session of a dead process ends.
+func (postgresDialect) forceUnlock(ctx context.Context, conn *sql.Conn) error {
+	return nil
+}
+
+// sqliteDialect has no advisory locks, so the lock is a row in a dedicated table whose primary
+// key makes a second insert fail.
+type sqliteDialect struct{}
+
+func (sqliteDialect) createTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			locked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	return err
+}
+
+func (sqliteDialect) lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations_lock (id) VALUES (1)`)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrLocked
+	}
+	return err
+}
+
+func (sqliteDialect) unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `DELETE FROM schema_migrations_lock`)
+	return err
+}
+
+func (d sqliteDialect) forceUnlock(ctx context.Context, conn *sql.Conn) error {
+	err := d.createTable(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return d.unlock(ctx, conn)
+}
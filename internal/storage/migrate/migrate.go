@@ -0,0 +1,341 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// NilVersion is the version of a database without any applied migration.
+const NilVersion int64 = -1
+
+var (
+	ErrNoChange = errors.New("no change")
+	ErrLocked   = errors.New("migrations are locked by another process")
+
+	fileRX = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down)\.sql$`)
+)
+
+// ErrDirty is returned when a previous migration failed half way. The schema has to be fixed by
+// hand and the version set with Force before migrating again.
+type ErrDirty struct {
+	Version int64
+}
+
+func (e ErrDirty) Error() string {
+	return fmt.Sprintf("database is dirty at version %d, fix it and force a version", e.Version)
+}
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version    int64
+	Dirty      bool
+	Migrations []*Migration
+}
+
+// Migrator applies the migrations of a directory and records the current version in the
+// schema_migrations table, in the same format as the migrate CLI used before, so existing
+// databases keep their version.
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []*Migration
+}
+
+// New reads the "{version}_{name}.up.sql" and "{version}_{name}.down.sql" files of dir in fsys.
+// The driver is either "postgres" or "sqlite".
+func New(db *sql.DB, driver string, fsys fs.FS, dir string) (*Migrator, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("migrations are not supported for the %q driver", driver)
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		matches := fileRX.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, found := byVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	m := &Migrator{db: db, dialect: d}
+	for _, migration := range byVersion {
+		m.migrations = append(m.migrations, migration)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+
+	return m, nil
+}
+
+// Up applies every pending migration and returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var applied []*Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		version, err := m.cleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+
+			err = m.run(ctx, conn, migration.Up, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+
+		if len(applied) == 0 {
+			return ErrNoChange
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns the reverted ones.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var reverted []*Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		version, err := m.cleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+
+			previous := NilVersion
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+
+			err = m.run(ctx, conn, migration.Down, previous)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+
+		if len(reverted) == 0 {
+			return ErrNoChange
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns the current version and every known migration.
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	err = m.dialect.createTable(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Status{Version: version, Dirty: dirty, Migrations: m.migrations}, nil
+}
+
+// Force sets the version without running any migration and clears the dirty flag.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// A process that died while migrating may have left its lock behind.
+	err = m.dialect.forceUnlock(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		err = writeVersion(ctx, tx, version, false)
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+// locked runs fn on a dedicated connection while holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = m.dialect.createTable(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	err = m.dialect.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	err = fn(conn)
+
+	// Release the lock even if ctx is already done.
+	unlockErr := m.dialect.unlock(context.Background(), conn)
+	if err == nil {
+		err = unlockErr
+	}
+
+	return err
+}
+
+func (m *Migrator) cleanVersion(ctx context.Context, conn *sql.Conn) (int64, error) {
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty {
+		return 0, ErrDirty{Version: version}
+	}
+
+	return version, nil
+}
+
+// run marks the database dirty at version, executes the migration in a transaction and clears
+// the dirty flag again. If the migration fails the database stays dirty.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, query string, version int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = writeVersion(ctx, tx, version, true)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	tx, err = conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	err = writeVersion(ctx, tx, version, false)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func readVersion(ctx context.Context, conn *sql.Conn) (int64, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return NilVersion, false, nil
+		default:
+			return 0, false, err
+		}
+	}
+
+	return version, dirty, nil
+}
+
+func writeVersion(ctx context.Context, tx *sql.Tx, version int64, dirty bool) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+
+	// Like the migrate CLI, a clean database without migrations has no row at all.
+	if version == NilVersion && !dirty {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, version, dirty)
+	return err
+}
@@ -0,0 +1,168 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+var testMigrations = fstest.MapFS{
+	"migrations/000001_create_a.up.sql":   {Data: []byte(`CREATE TABLE a (id INTEGER PRIMARY KEY);`)},
+	"migrations/000001_create_a.down.sql": {Data: []byte(`DROP TABLE a;`)},
+	"migrations/000002_create_b.up.sql":   {Data: []byte(`CREATE TABLE b (id INTEGER PRIMARY KEY);`)},
+	"migrations/000002_create_b.down.sql": {Data: []byte(`DROP TABLE b;`)},
+	"migrations/README.md":                {Data: []byte(`Not a migration.`)},
+}
+
+func newTestMigrator(t *testing.T, fsys fstest.MapFS) (*Migrator, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := New(db, "sqlite", fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m, db
+}
+
+func checkVersion(t *testing.T, m *Migrator, version int64, dirty bool) {
+	t.Helper()
+
+	status, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != version || status.Dirty != dirty {
+		t.Fatalf("got version %d, dirty %t, want %d, %t", status.Version, status.Dirty, version, dirty)
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var n int
+	err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = $1`, name).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n > 0
+}
+
+func TestUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	m, db := newTestMigrator(t, testMigrations)
+
+	checkVersion(t, m, NilVersion, false)
+
+	applied, err := m.Up(ctx)
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("got %d applied migrations and error %v, want 2", len(applied), err)
+	}
+	checkVersion(t, m, 2, false)
+	if !tableExists(t, db, "a") || !tableExists(t, db, "b") {
+		t.Fatal("tables of the migrations missing")
+	}
+
+	if _, err := m.Up(ctx); !errors.Is(err, ErrNoChange) {
+		t.Fatalf("got error %v migrating up again, want ErrNoChange", err)
+	}
+
+	reverted, err := m.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("got reverted %v and error %v, want migration 2", reverted, err)
+	}
+	checkVersion(t, m, 1, false)
+	if tableExists(t, db, "b") {
+		t.Fatal("table b still exists")
+	}
+
+	if _, err := m.Down(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	checkVersion(t, m, NilVersion, false)
+	if tableExists(t, db, "a") {
+		t.Fatal("table a still exists")
+	}
+
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrNoChange) {
+		t.Fatalf("got error %v migrating down an empty database, want ErrNoChange", err)
+	}
+}
+
+func TestFailedMigrationLeavesDatabaseDirty(t *testing.T) {
+	ctx := context.Background()
+
+	fsys := fstest.MapFS{
+		"migrations/000003_broken.up.sql":   {Data: []byte(`CREATE TABLE c (;`)},
+		"migrations/000003_broken.down.sql": {Data: []byte(`DROP TABLE c;`)},
+	}
+	for name, file := range testMigrations {
+		fsys[name] = file
+	}
+
+	m, _ := newTestMigrator(t, fsys)
+
+	applied, err := m.Up(ctx)
+	if err == nil {
+		t.Fatal("got no error for a broken migration")
+	}
+	if len(applied) != 2 {
+		t.Fatalf("got %d applied migrations, want the 2 before the broken one", len(applied))
+	}
+	checkVersion(t, m, 3, true)
+
+	var dirty ErrDirty
+	if _, err := m.Up(ctx); !errors.As(err, &dirty) || dirty.Version != 3 {
+		t.Fatalf("got error %v, want ErrDirty at version 3", err)
+	}
+	if _, err := m.Down(ctx, 1); !errors.As(err, &dirty) {
+		t.Fatalf("got error %v, want ErrDirty", err)
+	}
+
+	if err := m.Force(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	checkVersion(t, m, 2, false)
+}
+
+func TestLockedMigrations(t *testing.T) {
+	ctx := context.Background()
+	m, db := newTestMigrator(t, testMigrations)
+
+	// Another process holds the lock, or died while holding it.
+	if _, err := m.Status(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations_lock (id) VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(ctx); !errors.Is(err, ErrLocked) {
+		t.Fatalf("got error %v, want ErrLocked", err)
+	}
+	checkVersion(t, m, NilVersion, false)
+
+	// Force releases the stale lock.
+	if err := m.Force(ctx, NilVersion); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The lock is released after migrating.
+	if _, err := m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,54 +1,31 @@
 package storage
 
 import (
-	"context"
-	"database/sql"
-	"time"
+	"errors"
 
-	"github.com/lib/pq"
 	"github.com/yantay0/url-shortener/internal/model"
 )
 
-type PermissionsStorage struct {
-	DB *sql.DB
-}
-
-func (s PermissionsStorage) GetAllForUser(userID int64) (model.Permissions, error) {
-	query := `
-	SELECT permissions.code
-	FROM permissions
-	INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
-	INNER JOIN users ON users_permissions.user_id = users.id
-	WHERE users.id = $1`
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	rows, err := s.DB.QueryContext(ctx, query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var permissions model.Permissions
-	for rows.Next() {
-		var permission string
-		err := rows.Scan(&permission)
-		if err != nil {
-			return nil, err
-		}
-		permissions = append(permissions, permission)
-	}
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-	return permissions, nil
-}
+var (
+	ErrDuplicatePermission = errors.New("duplicate permission")
+	ErrRoleNotGranted      = errors.New("role not granted")
+)
 
-func (s PermissionsStorage) AddForUser(userID int64, codes ...string) error {
-	query := `
-	INSERT INTO users_permissions
-	SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	_, err := s.DB.ExecContext(ctx, query, userID, pq.Array(codes))
-	return err
+type PermissionsStorage interface {
+	// GetAllForUser returns the permissions granted to the user directly and through roles.
+	GetAllForUser(userID int64) (model.Permissions, error)
+	// AddForUser grants permissions directly, unknown and already granted codes are ignored.
+	AddForUser(userID int64, codes ...string) error
+	// RemoveForUser revokes direct grants, permissions of the user's roles are kept.
+	RemoveForUser(userID int64, codes ...string) error
+	GetRolesForUser(userID int64) ([]string, error)
+	// AddRoleForUser returns ErrRecordNotFound if the role doesn't exist.
+	AddRoleForUser(userID int64, role string) error
+	// RemoveRoleForUser returns ErrRecordNotFound if the role doesn't exist and ErrRoleNotGranted
+	// if the user doesn't have it.
+	RemoveRoleForUser(userID int64, role string) error
+	GetAll() (model.Permissions, error)
+	// Insert creates a permission code and grants it to the admin role. It returns
+	// ErrDuplicatePermission if the code exists.
+	Insert(code string) error
 }
@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+type ClicksStorage struct {
+	DB *sql.DB
+}
+
+// clickKey identifies the shortening of a click when counting the visits of a batch.
+type clickKey struct {
+	domain     string
+	identifier string
+}
+
+// InsertBatch stores a batch of clicks and increments the visits counter of every clicked
+// shortening by its number of clicks in the batch, all in one transaction. Clicks already counted
+// on redirect are left out of the visits.
+func (s ClicksStorage) InsertBatch(clicks []*model.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	var (
+		domains     = make([]string, len(clicks))
+		identifiers = make([]string, len(clicks))
+		clickedAt   = make([]string, len(clicks))
+		referrers   = make([]string, len(clicks))
+		uaFamilies  = make([]string, len(clicks))
+		devices     = make([]string, len(clicks))
+		ipHashes    = make([]string, len(clicks))
+		visits      = make(map[clickKey]int64)
+	)
+
+	for i, click := range clicks {
+		domains[i] = click.Domain
+		identifiers[i] = click.Identifier
+		clickedAt[i] = click.ClickedAt.Format(time.RFC3339Nano)
+		referrers[i] = click.Referrer
+		uaFamilies[i] = click.UAFamily
+		devices[i] = click.Device
+		ipHashes[i] = click.IPHash
+		if !click.Counted {
+			visits[clickKey{click.Domain, click.Identifier}]++
+		}
+	}
+
+	visitDomains := make([]string, 0, len(visits))
+	visitIdentifiers := make([]string, 0, len(visits))
+	visitCounts := make([]int64, 0, len(visits))
+	for key, count := range visits {
+		visitDomains = append(visitDomains, key.domain)
+		visitIdentifiers = append(visitIdentifiers, key.identifier)
+		visitCounts = append(visitCounts, count)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Clicks of shortenings deleted in the meantime are skipped by the join.
+	insertQuery := `
+		INSERT INTO clicks (domain, identifier, clicked_at, referrer, ua_family, device, ip_hash)
+		SELECT c.domain, c.identifier, c.clicked_at, c.referrer, c.ua_family, c.device, c.ip_hash
+		FROM unnest($1::text[], $2::text[], $3::timestamptz[], $4::text[], $5::text[], $6::text[], $7::text[])
+			AS c (domain, identifier, clicked_at, referrer, ua_family, device, ip_hash)
+		INNER JOIN shortening ON shortening.domain = c.domain AND shortening.identifier = c.identifier`
+
+	_, err = tx.ExecContext(ctx, insertQuery,
+		pq.Array(domains),
+		pq.Array(identifiers),
+		pq.Array(clickedAt),
+		pq.Array(referrers),
+		pq.Array(uaFamilies),
+		pq.Array(devices),
+		pq.Array(ipHashes),
+	)
+	if err != nil {
+		return err
+	}
+
+	updateQuery := `
+		UPDATE shortening
+		SET visits = shortening.visits + v.count
+		FROM unnest($1::text[], $2::text[], $3::bigint[]) AS v (domain, identifier, count)
+		WHERE shortening.domain = v.domain AND shortening.identifier = v.identifier`
+
+	_, err = tx.ExecContext(ctx, updateQuery, pq.Array(visitDomains), pq.Array(visitIdentifiers), pq.Array(visitCounts))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Stats returns the clicks of a shortening in [from, to) bucketed by interval, which must be
+// one of model.StatsIntervals, together with the referrer, browser and device breakdowns.
+func (s ClicksStorage) Stats(domain, identifier, interval string, from, to time.Time) (*model.ClickStats, error) {
+	stats := &model.ClickStats{
+		Interval:   interval,
+		From:       from,
+		To:         to,
+		Buckets:    []*model.ClickBucket{},
+		Referrers:  []*model.ClickCount{},
+		UAFamilies: []*model.ClickCount{},
+		Devices:    []*model.ClickCount{},
+	}
+
+	query := `
+		SELECT date_trunc($3, clicked_at) AS bucket, count(*)
+		FROM clicks
+		WHERE domain = $1 AND identifier = $2 AND clicked_at >= $4 AND clicked_at < $5
+		GROUP BY bucket
+		ORDER BY bucket`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, domain, identifier, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket model.ClickBucket
+		err := rows.Scan(&bucket.Start, &bucket.Clicks)
+		if err != nil {
+			return nil, err
+		}
+		stats.Total += bucket.Clicks
+		stats.Buckets = append(stats.Buckets, &bucket)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	breakdowns := []struct {
+		column string
+		dst    *[]*model.ClickCount
+	}{
+		{"referrer", &stats.Referrers},
+		{"ua_family", &stats.UAFamilies},
+		{"device", &stats.Devices},
+	}
+
+	for _, breakdown := range breakdowns {
+		*breakdown.dst, err = s.countBy(ctx, breakdown.column, domain, identifier, from, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+// countBy returns the ten most frequent values of column among the clicks of a shortening.
+// The column is never user input.
+func (s ClicksStorage) countBy(ctx context.Context, column, domain, identifier string, from, to time.Time) ([]*model.ClickCount, error) {
+	query := fmt.Sprintf(`
+		SELECT %[1]s, count(*)
+		FROM clicks
+		WHERE domain = $1 AND identifier = $2 AND clicked_at >= $3 AND clicked_at < $4
+		GROUP BY %[1]s
+		ORDER BY count(*) DESC, %[1]s ASC
+		LIMIT 10`, column)
+
+	rows, err := s.DB.QueryContext(ctx, query, domain, identifier, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []*model.ClickCount{}
+	for rows.Next() {
+		var count model.ClickCount
+		err := rows.Scan(&count.Value, &count.Clicks)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, &count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type PermissionsStorage struct {
+	DB *sql.DB
+}
+
+func (s PermissionsStorage) GetAllForUser(userID int64) (model.Permissions, error) {
+	query := `
+	SELECT permissions.code
+	FROM permissions
+	INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+	WHERE users_permissions.user_id = $1
+	UNION
+	SELECT permissions.code
+	FROM permissions
+	INNER JOIN roles_permissions ON roles_permissions.permission_id = permissions.id
+	INNER JOIN users_roles ON users_roles.role_id = roles_permissions.role_id
+	WHERE users_roles.user_id = $1
+	ORDER BY code`
+
+	return s.queryStrings(query, userID)
+}
+
+func (s PermissionsStorage) AddForUser(userID int64, codes ...string) error {
+	query := `
+	INSERT INTO users_permissions
+	SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+	ON CONFLICT DO NOTHING`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := s.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	return err
+}
+
+func (s PermissionsStorage) RemoveForUser(userID int64, codes ...string) error {
+	query := `
+	DELETE FROM users_permissions
+	USING permissions
+	WHERE users_permissions.permission_id = permissions.id
+		AND users_permissions.user_id = $1
+		AND permissions.code = ANY($2)`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := s.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	return err
+}
+
+func (s PermissionsStorage) GetRolesForUser(userID int64) ([]string, error) {
+	query := `
+	SELECT roles.name
+	FROM roles
+	INNER JOIN users_roles ON users_roles.role_id = roles.id
+	WHERE users_roles.user_id = $1
+	ORDER BY roles.name`
+
+	return s.queryStrings(query, userID)
+}
+
+func (s PermissionsStorage) AddRoleForUser(userID int64, role string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var roleID int64
+	err := s.DB.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = $1`, role).Scan(&roleID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return storage.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query := `
+	INSERT INTO users_roles (user_id, role_id) VALUES ($1, $2)
+	ON CONFLICT DO NOTHING`
+	_, err = s.DB.ExecContext(ctx, query, userID, roleID)
+	return err
+}
+
+func (s PermissionsStorage) RemoveRoleForUser(userID int64, role string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var roleID int64
+	err := s.DB.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = $1`, role).Scan(&roleID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return storage.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	result, err := s.DB.ExecContext(ctx, `DELETE FROM users_roles WHERE user_id = $1 AND role_id = $2`, userID, roleID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrRoleNotGranted
+	}
+
+	return nil
+}
+
+func (s PermissionsStorage) GetAll() (model.Permissions, error) {
+	return s.queryStrings(`SELECT code FROM permissions ORDER BY code`)
+}
+
+func (s PermissionsStorage) Insert(code string) error {
+	query := `
+	WITH permission AS (
+		INSERT INTO permissions (code) VALUES ($1) RETURNING id
+	)
+	INSERT INTO roles_permissions
+	SELECT roles.id, permission.id FROM roles, permission WHERE roles.name = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, code, model.RoleAdmin)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `permissions_code_idx`):
+			return storage.ErrDuplicatePermission
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryStrings returns the single text column of all rows of a query.
+func (s PermissionsStorage) queryStrings(query string, args ...interface{}) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := []string{}
+	for rows.Next() {
+		var value string
+		err := rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
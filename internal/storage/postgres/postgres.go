@@ -8,8 +8,21 @@ import (
 
 	_ "github.com/lib/pq" // init driver
 	"github.com/yantay0/url-shortener/internal/config"
+	"github.com/yantay0/url-shortener/internal/storage"
 )
 
+// New returns the Postgres implementation of every repository.
+func New(db *sql.DB) storage.Storage {
+	return storage.Storage{
+		Shortenings: &ShorteningsStorage{DB: db},
+		Permissions: PermissionsStorage{DB: db},
+		Tokens:      TokenStorage{DB: db},
+		Users:       UserStorage{DB: db},
+		Clicks:      ClicksStorage{DB: db},
+		Domains:     DomainsStorage{DB: db},
+	}
+}
+
 func OpenDB(cfg *config.Config) (*sql.DB, error) {
 
 	const op = "storage.postgres.OpenDB"
@@ -0,0 +1,193 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+)
+
+// bucketExpressions replace Postgres' date_trunc, weeks start on Monday like in Postgres.
+var bucketExpressions = map[string]string{
+	"hour": `strftime('%Y-%m-%d %H:00:00', clicked_at)`,
+	"day":  `strftime('%Y-%m-%d 00:00:00', clicked_at)`,
+	"week": `strftime('%Y-%m-%d 00:00:00', clicked_at, 'weekday 0', '-6 days')`,
+}
+
+type ClicksStorage struct {
+	DB *sql.DB
+}
+
+// clickKey identifies the shortening of a click when counting the visits of a batch.
+type clickKey struct {
+	domain     string
+	identifier string
+}
+
+// InsertBatch stores a batch of clicks and increments the visits counter of every clicked
+// shortening by its number of clicks in the batch, all in one transaction.
+func (s ClicksStorage) InsertBatch(clicks []*model.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Clicks of shortenings deleted in the meantime are skipped.
+	insert, err := tx.PrepareContext(ctx, `
+		INSERT INTO clicks (domain, identifier, clicked_at, referrer, ua_family, device, ip_hash)
+		SELECT $1, $2, $3, $4, $5, $6, $7
+		WHERE EXISTS (SELECT 1 FROM shortening WHERE domain = $1 AND identifier = $2)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	visits := make(map[clickKey]int64)
+
+	for _, click := range clicks {
+		_, err = insert.ExecContext(ctx, click.Domain, click.Identifier, click.ClickedAt, click.Referrer, click.UAFamily, click.Device, click.IPHash)
+		if err != nil {
+			return err
+		}
+		if !click.Counted {
+			visits[clickKey{click.Domain, click.Identifier}]++
+		}
+	}
+
+	update, err := tx.PrepareContext(ctx, `
+		UPDATE shortening
+		SET visits = visits + $1
+		WHERE domain = $2 AND identifier = $3`)
+	if err != nil {
+		return err
+	}
+	defer update.Close()
+
+	for key, count := range visits {
+		_, err = update.ExecContext(ctx, count, key.domain, key.identifier)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Stats returns the clicks of a shortening in [from, to) bucketed by interval, which must be
+// one of model.StatsIntervals, together with the referrer, browser and device breakdowns.
+func (s ClicksStorage) Stats(domain, identifier, interval string, from, to time.Time) (*model.ClickStats, error) {
+	stats := &model.ClickStats{
+		Interval:   interval,
+		From:       from,
+		To:         to,
+		Buckets:    []*model.ClickBucket{},
+		Referrers:  []*model.ClickCount{},
+		UAFamilies: []*model.ClickCount{},
+		Devices:    []*model.ClickCount{},
+	}
+
+	bucket, ok := bucketExpressions[interval]
+	if !ok {
+		return nil, fmt.Errorf("unsupported stats interval %q", interval)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s AS bucket, count(*)
+		FROM clicks
+		WHERE domain = $1 AND identifier = $2 AND datetime(clicked_at) >= datetime($3) AND datetime(clicked_at) < datetime($4)
+		GROUP BY bucket
+		ORDER BY bucket`, bucket)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, domain, identifier, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			start  string
+			bucket model.ClickBucket
+		)
+		err := rows.Scan(&start, &bucket.Clicks)
+		if err != nil {
+			return nil, err
+		}
+
+		// The bucket is an expression without a column type, so the driver returns it as text.
+		bucket.Start, err = time.Parse(time.DateTime, start)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.Total += bucket.Clicks
+		stats.Buckets = append(stats.Buckets, &bucket)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	breakdowns := []struct {
+		column string
+		dst    *[]*model.ClickCount
+	}{
+		{"referrer", &stats.Referrers},
+		{"ua_family", &stats.UAFamilies},
+		{"device", &stats.Devices},
+	}
+
+	for _, breakdown := range breakdowns {
+		*breakdown.dst, err = s.countBy(ctx, breakdown.column, domain, identifier, from, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+// countBy returns the ten most frequent values of column among the clicks of a shortening.
+// The column is never user input.
+func (s ClicksStorage) countBy(ctx context.Context, column, domain, identifier string, from, to time.Time) ([]*model.ClickCount, error) {
+	query := fmt.Sprintf(`
+		SELECT %[1]s, count(*)
+		FROM clicks
+		WHERE domain = $1 AND identifier = $2 AND datetime(clicked_at) >= datetime($3) AND datetime(clicked_at) < datetime($4)
+		GROUP BY %[1]s
+		ORDER BY count(*) DESC, %[1]s ASC
+		LIMIT 10`, column)
+
+	rows, err := s.DB.QueryContext(ctx, query, domain, identifier, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []*model.ClickCount{}
+	for rows.Next() {
+		var count model.ClickCount
+		err := rows.Scan(&count.Value, &count.Clicks)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, &count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type DomainsStorage struct {
+	DB *sql.DB
+}
+
+func (s DomainsStorage) Insert(domain *model.Domain) error {
+	query := `
+		INSERT INTO domains (host, user_id, verification_token)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS (SELECT 1 FROM domains WHERE host = $1 AND verified_at IS NOT NULL)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, domain.Host, domain.UserID, domain.VerificationToken).Scan(&domain.ID, &domain.CreatedAt)
+	if err != nil {
+		switch {
+		// No row is inserted if the host is verified already.
+		case errors.Is(err, sql.ErrNoRows), strings.Contains(err.Error(), `UNIQUE constraint failed: domains.host`):
+			return storage.ErrDuplicateDomain
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s DomainsStorage) Get(id int64) (*model.Domain, error) {
+	query := `
+		SELECT id, created_at, host, user_id, verification_token, verified_at
+		FROM domains
+		WHERE id = $1`
+
+	return s.get(query, id)
+}
+
+func (s DomainsStorage) GetByHost(host string) (*model.Domain, error) {
+	query := `
+		SELECT id, created_at, host, user_id, verification_token, verified_at
+		FROM domains
+		WHERE host = $1 AND verified_at IS NOT NULL`
+
+	return s.get(query, host)
+}
+
+func (s DomainsStorage) get(query string, arg interface{}) (*model.Domain, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var domain model.Domain
+	err := s.DB.QueryRowContext(ctx, query, arg).Scan(
+		&domain.ID,
+		&domain.CreatedAt,
+		&domain.Host,
+		&domain.UserID,
+		&domain.VerificationToken,
+		&domain.VerifiedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, storage.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &domain, nil
+}
+
+func (s DomainsStorage) GetAllForUser(userID int64) ([]*model.Domain, error) {
+	query := `
+		SELECT id, created_at, host, user_id, verification_token, verified_at
+		FROM domains
+		WHERE user_id = $1
+		ORDER BY host`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	domains := []*model.Domain{}
+	for rows.Next() {
+		var domain model.Domain
+		err := rows.Scan(
+			&domain.ID,
+			&domain.CreatedAt,
+			&domain.Host,
+			&domain.UserID,
+			&domain.VerificationToken,
+			&domain.VerifiedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, &domain)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return domains, nil
+}
+
+func (s DomainsStorage) MarkVerified(domain *model.Domain) error {
+	query := `
+		UPDATE domains
+		SET verified_at = datetime('now')
+		WHERE id = $1
+		RETURNING verified_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, domain.ID).Scan(&domain.VerifiedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return storage.ErrRecordNotFound
+		case strings.Contains(err.Error(), `UNIQUE constraint failed: domains.host`):
+			return storage.ErrDuplicateDomain
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s DomainsStorage) Delete(domain *model.Domain) error {
+	query := `
+		DELETE FROM domains
+		WHERE id = $1
+		RETURNING verified_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var verifiedAt *time.Time
+	err = tx.QueryRowContext(ctx, query, domain.ID).Scan(&verifiedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return storage.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	// Pending claims of a host don't own any shortenings on it, the verified domain does.
+	if verifiedAt != nil {
+		query = `
+		DELETE FROM shortening
+		WHERE domain = $1`
+
+		_, err = tx.ExecContext(ctx, query, domain.Host)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
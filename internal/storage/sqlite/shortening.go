@@ -0,0 +1,547 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type ShorteningsStorage struct {
+	DB *sql.DB
+}
+
+func (s *ShorteningsStorage) Insert(shortening *model.Shortening) error {
+	return s.SaveUserShortening(shortening)
+}
+
+func (s *ShorteningsStorage) Get(domain, identifier string) (*model.Shortening, error) {
+	if identifier == "" {
+		return nil, storage.ErrRecordNotFound
+	}
+
+	query := `
+		SELECT identifier, domain, created_at, original_url, version, user_id, visits, redirect_type,
+			expires_at, max_visits, archived_at, password_hash, url_hash, safety_verdict, safety_reason,
+			last_checked_at, last_status, healthy, preview_title, preview_description, preview_image
+		FROM shortening 
+		WHERE domain = $1 AND identifier = $2`
+
+	var shortening model.Shortening
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, domain, identifier).Scan(
+		&shortening.Identifier,
+		&shortening.Domain,
+		&shortening.CreatedAt,
+		&shortening.OriginalURL,
+		&shortening.Version,
+		&shortening.UserID,
+		&shortening.Visits,
+		&shortening.RedirectType,
+		&shortening.ExpiresAt,
+		&shortening.MaxVisits,
+		&shortening.ArchivedAt,
+		&shortening.Password.Hash,
+		&shortening.URLHash,
+		&shortening.SafetyVerdict,
+		&shortening.SafetyReason,
+		&shortening.LastCheckedAt,
+		&shortening.LastStatus,
+		&shortening.Healthy,
+		&shortening.PreviewTitle,
+		&shortening.PreviewDescription,
+		&shortening.PreviewImage,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, storage.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &shortening, err
+}
+
+// Update stores the editable fields of the shortening. A new expiry or visit limit unarchives it,
+// the sweeper archives it again if it is still expired.
+func (s *ShorteningsStorage) Update(shortening *model.Shortening) error {
+	query := `
+		UPDATE shortening
+		SET original_url = $1, redirect_type = $2, expires_at = $3, max_visits = $4, password_hash = $5,
+			url_hash = $6, safety_verdict = $7, safety_reason = $8, version = version + 1,
+			next_check_at = CASE WHEN original_url = $1 THEN next_check_at END,
+			archived_at = CASE WHEN expires_at IS $3 AND max_visits IS $4 THEN archived_at END
+		WHERE domain = $9 AND identifier = $10 AND version = $11
+		RETURNING version, archived_at`
+
+	args := []interface{}{
+		shortening.OriginalURL,
+		shortening.RedirectType,
+		shortening.ExpiresAt,
+		shortening.MaxVisits,
+		shortening.Password.Hash,
+		shortening.URLHash,
+		shortening.SafetyVerdict,
+		shortening.SafetyReason,
+		shortening.Domain,
+		shortening.Identifier,
+		shortening.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, args...).Scan(&shortening.Version, &shortening.ArchivedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return storage.ErrEditConflict
+		case strings.Contains(err.Error(), `UNIQUE constraint failed: shortening.user_id, shortening.domain, shortening.url_hash`):
+			return storage.ErrDuplicateURL
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *ShorteningsStorage) Delete(domain, Identifier string) error {
+	if Identifier == "" {
+		return storage.ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM shortening
+		WHERE domain = $1 AND identifier = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, domain, Identifier)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (s *ShorteningsStorage) GetAll(OriginalURL string, healthy *bool, userID int64, filters model.Filters) ([]*model.Shortening, model.Metadata, error) {
+	// order by id for the consistent ordering
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), identifier, domain, created_at, original_url, version, user_id, redirect_type,
+			expires_at, max_visits, archived_at, safety_verdict, safety_reason,
+			last_checked_at, last_status, healthy
+		FROM shortening
+		WHERE (LOWER(original_url) = LOWER($1) OR $1 = '')
+			AND ($2 IS NULL OR healthy = $2)
+			AND ($3 = 0 OR user_id = $3)
+		ORDER BY %s %s, identifier ASC, domain ASC
+		LIMIT $4 OFFSET $5`, filters.SortColumn(), filters.SortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{OriginalURL, healthy, userID, filters.Limit(), filters.Offset()}
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, model.Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	shortenings := []*model.Shortening{}
+
+	for rows.Next() {
+		var shortening model.Shortening
+		err := rows.Scan(
+			&totalRecords,
+			&shortening.Identifier,
+			&shortening.Domain,
+			&shortening.CreatedAt,
+			&shortening.OriginalURL,
+			&shortening.Version,
+			&shortening.UserID,
+			&shortening.RedirectType,
+			&shortening.ExpiresAt,
+			&shortening.MaxVisits,
+			&shortening.ArchivedAt,
+			&shortening.SafetyVerdict,
+			&shortening.SafetyReason,
+			&shortening.LastCheckedAt,
+			&shortening.LastStatus,
+			&shortening.Healthy,
+		)
+
+		if err != nil {
+			return nil, model.Metadata{}, err
+		}
+		shortenings = append(shortenings, &shortening)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, model.Metadata{}, err
+	}
+
+	metadata := model.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return shortenings, metadata, nil
+}
+
+func (s *ShorteningsStorage) GetUserAllShortenings(userID int64) ([]*model.Shortening, error) {
+	query := `
+	SELECT created_at, original_url, identifier, domain, version, user_id, redirect_type,
+		expires_at, max_visits, archived_at, safety_verdict, safety_reason,
+			last_checked_at, last_status, healthy
+	FROM shortening
+	WHERE user_id = $1
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{userID}
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	shortenings := []*model.Shortening{}
+
+	for rows.Next() {
+		var shortening model.Shortening
+		err := rows.Scan(
+			&shortening.CreatedAt,
+			&shortening.OriginalURL,
+			&shortening.Identifier,
+			&shortening.Domain,
+			&shortening.Version,
+			&shortening.UserID,
+			&shortening.RedirectType,
+			&shortening.ExpiresAt,
+			&shortening.MaxVisits,
+			&shortening.ArchivedAt,
+			&shortening.SafetyVerdict,
+			&shortening.SafetyReason,
+			&shortening.LastCheckedAt,
+			&shortening.LastStatus,
+			&shortening.Healthy,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+		shortenings = append(shortenings, &shortening)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shortenings, nil
+}
+
+func (s *ShorteningsStorage) SaveUserShortening(shortening *model.Shortening) error {
+	query := `
+		INSERT INTO shortening (identifier, original_url, user_id, redirect_type, expires_at, max_visits, password_hash, url_hash,
+			safety_verdict, safety_reason, domain)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING identifier, created_at, version`
+
+	args := []interface{}{
+		shortening.Identifier,
+		shortening.OriginalURL,
+		shortening.UserID,
+		shortening.RedirectType,
+		shortening.ExpiresAt,
+		shortening.MaxVisits,
+		shortening.Password.Hash,
+		shortening.URLHash,
+		shortening.SafetyVerdict,
+		shortening.SafetyReason,
+		shortening.Domain,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := s.DB.QueryRowContext(ctx, query, args...).Scan(&shortening.Identifier, &shortening.CreatedAt, &shortening.Version)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `UNIQUE constraint failed: shortening.user_id, shortening.domain, shortening.url_hash`):
+			return storage.ErrDuplicateURL
+		case strings.Contains(err.Error(), `UNIQUE constraint failed: shortening.domain, shortening.identifier`):
+			return storage.ErrIdentifierExists
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ShorteningsStorage) GetByURLHash(userID int64, domain, urlHash string) (*model.Shortening, error) {
+	query := `
+		SELECT identifier
+		FROM shortening
+		WHERE user_id = $1 AND domain = $2 AND url_hash = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var identifier string
+	err := s.DB.QueryRowContext(ctx, query, userID, domain, urlHash).Scan(&identifier)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, storage.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return s.Get(domain, identifier)
+}
+
+// GetOriginalUrl looks up the redirect target of a shortening. It doesn't count the visit, clicks
+// are recorded in batches by the click recorder and the visits of links with max_visits are
+// counted by CountVisit.
+func (s *ShorteningsStorage) GetOriginalUrl(domain, identifier string) (*model.Shortening, error) {
+	if identifier == "" {
+		return nil, storage.ErrRecordNotFound
+	}
+
+	query := `
+		SELECT original_url, visits, redirect_type, expires_at, max_visits, archived_at, password_hash,
+			safety_verdict, safety_reason
+		FROM shortening 
+		WHERE domain = $1 AND identifier = $2`
+
+	var shortening model.Shortening
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, domain, identifier).Scan(
+		&shortening.OriginalURL,
+		&shortening.Visits,
+		&shortening.RedirectType,
+		&shortening.ExpiresAt,
+		&shortening.MaxVisits,
+		&shortening.ArchivedAt,
+		&shortening.Password.Hash,
+		&shortening.SafetyVerdict,
+		&shortening.SafetyReason,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, storage.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	shortening.Domain = domain
+	shortening.Identifier = identifier
+
+	if shortening.Expired(time.Now()) {
+		return nil, storage.ErrLinkExpired
+	}
+
+	return &shortening, nil
+}
+
+// CountVisit increments the visits of a shortening unless it has used up its maximum number of
+// visits, in a single statement so that concurrent redirects can't go past the limit.
+func (s *ShorteningsStorage) CountVisit(domain, identifier string) error {
+	query := `
+		UPDATE shortening
+		SET visits = visits + 1
+		WHERE domain = $1 AND identifier = $2 AND (max_visits IS NULL OR visits < max_visits)
+		RETURNING visits`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var visits int64
+	err := s.DB.QueryRowContext(ctx, query, domain, identifier).Scan(&visits)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return storage.ErrLinkExpired
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordVerdict stores the outcome of a safety check made outside of an edit, so the version
+// isn't incremented.
+func (s *ShorteningsStorage) RecordVerdict(domain, identifier, verdict, reason string) error {
+	query := `
+		UPDATE shortening
+		SET safety_verdict = $1, safety_reason = $2
+		WHERE domain = $3 AND identifier = $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, verdict, reason, domain, identifier)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetDueForHealthCheck returns up to limit active shortenings whose destination is due to be
+// checked, the ones that were never checked first. Only the fields needed by the health checker
+// are set.
+func (s *ShorteningsStorage) GetDueForHealthCheck(limit int) ([]*model.Shortening, error) {
+	query := `
+		SELECT domain, identifier, original_url, check_failures
+		FROM shortening
+		WHERE archived_at IS NULL AND (next_check_at IS NULL OR datetime(next_check_at) <= datetime('now'))
+		ORDER BY next_check_at, identifier
+		LIMIT $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	shortenings := []*model.Shortening{}
+
+	for rows.Next() {
+		var shortening model.Shortening
+		err := rows.Scan(&shortening.Domain, &shortening.Identifier, &shortening.OriginalURL, &shortening.CheckFailures)
+		if err != nil {
+			return nil, err
+		}
+		shortenings = append(shortenings, &shortening)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shortenings, nil
+}
+
+// RecordHealth stores the outcome of a destination health check. Like RecordVerdict it doesn't
+// create a new version of the shortening.
+func (s *ShorteningsStorage) RecordHealth(shortening *model.Shortening) error {
+	query := `
+		UPDATE shortening
+		SET last_checked_at = $1, last_status = $2, healthy = $3, check_failures = $4, next_check_at = $5
+		WHERE domain = $6 AND identifier = $7`
+
+	args := []interface{}{
+		shortening.LastCheckedAt,
+		shortening.LastStatus,
+		shortening.Healthy,
+		shortening.CheckFailures,
+		shortening.NextCheckAt,
+		shortening.Domain,
+		shortening.Identifier,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// RecordPreview stores the metadata of the destination page fetched for the preview page.
+func (s *ShorteningsStorage) RecordPreview(shortening *model.Shortening) error {
+	query := `
+		UPDATE shortening
+		SET preview_title = $1, preview_description = $2, preview_image = $3
+		WHERE domain = $4 AND identifier = $5`
+
+	args := []interface{}{
+		shortening.PreviewTitle,
+		shortening.PreviewDescription,
+		shortening.PreviewImage,
+		shortening.Domain,
+		shortening.Identifier,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// ArchiveExpired marks every shortening that passed its expiry time or its maximum number of
+// visits as archived and returns the number of archived rows.
+func (s *ShorteningsStorage) ArchiveExpired() (int64, error) {
+	query := `
+		UPDATE shortening
+		SET archived_at = datetime('now')
+		WHERE archived_at IS NULL
+			AND ((expires_at IS NOT NULL AND datetime(expires_at) <= datetime('now'))
+				OR (max_visits IS NOT NULL AND visits >= max_visits))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// NextID emulates a sequence with an AUTOINCREMENT table, whose ids are never reused even
+// though the rows are deleted right away.
+func (s *ShorteningsStorage) NextID() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var id int64
+	err := s.DB.QueryRowContext(ctx, `INSERT INTO shortening_identifier_seq DEFAULT VALUES RETURNING id`).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.DB.ExecContext(ctx, `DELETE FROM shortening_identifier_seq WHERE id = $1`, id)
+	return id, err
+}
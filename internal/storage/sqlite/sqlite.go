@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/config"
+	"github.com/yantay0/url-shortener/internal/storage"
+	_ "modernc.org/sqlite" // init driver
+)
+
+// pragmas are applied to every connection of the pool. Foreign keys are off by default in
+// SQLite, and the sqlite time format keeps stored timestamps readable by datetime().
+const pragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+
+func OpenDB(cfg *config.Config) (*sql.DB, error) {
+	const op = "storage.sqlite.OpenDB"
+
+	dsn := cfg.DB.Dsn
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + pragmas
+	} else {
+		dsn += "?" + pragmas
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+
+	duration, err := time.ParseDuration(cfg.DB.MaxIdleTime)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetConnMaxIdleTime(duration)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return db, nil
+}
+
+// New returns the SQLite implementation of every repository.
+func New(db *sql.DB) storage.Storage {
+	return storage.Storage{
+		Shortenings: &ShorteningsStorage{DB: db},
+		Permissions: PermissionsStorage{DB: db},
+		Tokens:      TokenStorage{DB: db},
+		Users:       UserStorage{DB: db},
+		Clicks:      ClicksStorage{DB: db},
+		Domains:     DomainsStorage{DB: db},
+	}
+}
@@ -0,0 +1,36 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/yantay0/url-shortener/internal/config"
+	"github.com/yantay0/url-shortener/internal/storage/migrate"
+	"github.com/yantay0/url-shortener/internal/storage/storagetest"
+	"github.com/yantay0/url-shortener/migrations"
+)
+
+func TestStorage(t *testing.T) {
+	var cfg config.Config
+	cfg.DB.Dsn = "file:" + filepath.Join(t.TempDir(), "test.db")
+	cfg.DB.MaxOpenConns = 4
+	cfg.DB.MaxIdleConns = 4
+	cfg.DB.MaxIdleTime = "15m"
+
+	db, err := OpenDB(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, "sqlite", migrations.FS, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Run(t, New(db))
+}
@@ -0,0 +1,229 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type TokenStorage struct {
+	DB *sql.DB
+}
+
+func (s TokenStorage) New(userID int64, ttl time.Duration, scope string) (*model.Token, error) {
+	token, err := model.GenerateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Insert(token)
+	return token, err
+}
+
+func (s TokenStorage) Insert(token *model.Token) error {
+	query := `
+	INSERT INTO tokens (hash, user_id, name, expiry, scope, user_agent)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+	args := []interface{}{token.Hash, token.UserID, token.Name, token.Expiry, token.Scope, token.UserAgent}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if len(token.Permissions) > 0 {
+		// SQLite has no array parameters, the codes are passed as a JSON array instead.
+		codesJSON, err := json.Marshal(token.Permissions)
+		if err != nil {
+			return err
+		}
+		query = `
+		INSERT INTO tokens_permissions
+		SELECT $1, permissions.id FROM permissions WHERE permissions.code IN (SELECT value FROM json_each($2))`
+		_, err = tx.ExecContext(ctx, query, token.ID, string(codesJSON))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s TokenStorage) DeleteAllForUser(scope string, userID int64) error {
+	query := `
+	DELETE FROM tokens
+	WHERE scope = $1 AND user_id = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := s.DB.ExecContext(ctx, query, scope, userID)
+	return err
+}
+
+func (s TokenStorage) GetForPlaintext(scope, tokenPlaintext string) (*model.Token, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+	SELECT id, hash, user_id, name, created_at, expiry, last_used_at, scope, user_agent
+	FROM tokens
+	WHERE hash = $1 AND scope = $2 AND (expiry IS NULL OR datetime(expiry) > datetime($3))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var token model.Token
+	err := s.DB.QueryRowContext(ctx, query, tokenHash[:], scope, time.Now()).Scan(
+		&token.ID,
+		&token.Hash,
+		&token.UserID,
+		&token.Name,
+		&token.CreatedAt,
+		&token.Expiry,
+		&token.LastUsedAt,
+		&token.Scope,
+		&token.UserAgent,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, storage.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	token.Permissions, err = s.getPermissions(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s TokenStorage) GetAllForUser(scope string, userID int64) ([]*model.Token, error) {
+	query := `
+	SELECT id, hash, user_id, name, created_at, expiry, last_used_at, scope, user_agent
+	FROM tokens
+	WHERE scope = $1 AND user_id = $2 AND (expiry IS NULL OR datetime(expiry) > datetime($3))
+	ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, scope, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*model.Token{}
+	for rows.Next() {
+		var token model.Token
+		err := rows.Scan(
+			&token.ID,
+			&token.Hash,
+			&token.UserID,
+			&token.Name,
+			&token.CreatedAt,
+			&token.Expiry,
+			&token.LastUsedAt,
+			&token.Scope,
+			&token.UserAgent,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		token.Permissions, err = s.getPermissions(ctx, token.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, nil
+}
+
+func (s TokenStorage) DeleteForUser(scope string, id, userID int64) error {
+	query := `
+	DELETE FROM tokens
+	WHERE scope = $1 AND id = $2 AND user_id = $3`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, scope, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (s TokenStorage) MarkUsed(token *model.Token) error {
+	query := `
+	UPDATE tokens
+	SET last_used_at = datetime('now')
+	WHERE id = $1
+	RETURNING last_used_at`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return s.DB.QueryRowContext(ctx, query, token.ID).Scan(&token.LastUsedAt)
+}
+
+// getPermissions returns the permissions a token is restricted to, an empty slice for tokens
+// that aren't restricted.
+func (s TokenStorage) getPermissions(ctx context.Context, tokenID int64) (model.Permissions, error) {
+	query := `
+	SELECT permissions.code
+	FROM permissions
+	INNER JOIN tokens_permissions ON tokens_permissions.permission_id = permissions.id
+	WHERE tokens_permissions.token_id = $1
+	ORDER BY permissions.code`
+
+	rows, err := s.DB.QueryContext(ctx, query, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := model.Permissions{}
+	for rows.Next() {
+		var permission string
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
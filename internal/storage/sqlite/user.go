@@ -0,0 +1,196 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+type UserStorage struct {
+	DB *sql.DB
+}
+
+func (s UserStorage) Insert(user *model.User) error {
+	query := `
+		INSERT INTO users (name, email, password_hash, activated, dedupe)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{user.Name, user.Email, user.Password.Hash, user.Activated, user.Dedupe}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `UNIQUE constraint failed: users.email`):
+			return storage.ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s UserStorage) GetByEmail(email string) (*model.User, error) {
+	query := `
+	SELECT id, created_at, name, email, password_hash, activated, dedupe, version
+	FROM users
+	WHERE email = $1`
+
+	var user model.User
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.Hash,
+		&user.Activated,
+		&user.Dedupe,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, storage.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+func (s UserStorage) GetByID(id int64) (*model.User, error) {
+	query := `
+	SELECT id, created_at, name, email, password_hash, activated, dedupe, version
+	FROM users
+	WHERE id = $1`
+
+	var user model.User
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.Hash,
+		&user.Activated,
+		&user.Dedupe,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, storage.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+func (s UserStorage) Update(user *model.User) error {
+	query := `
+	UPDATE users
+	SET name = $1, email = $2, password_hash = $3, activated = $4, dedupe = $5, version = version + 1
+	WHERE id = $6 AND version = $7
+	RETURNING version`
+
+	args := []interface{}{
+		user.Name,
+		user.Email,
+		user.Password.Hash,
+		user.Activated,
+		user.Dedupe,
+		user.ID,
+		user.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := s.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `UNIQUE constraint failed: users.email`):
+			return storage.ErrDuplicateEmail
+		case errors.Is(err, sql.ErrNoRows):
+			return storage.ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s UserStorage) GetForToken(tokenScope, tokenPlaintext string) (*model.User, error) {
+	// Calculate the SHA-256 hash for the plaintext token provided by the client.
+	// Note, that this will return a byte *array* with length 32, not a slice.
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		SELECT 
+			users.id, users.created_at, users.name, users.email, 
+			users.password_hash, users.activated, users.dedupe, users.version
+		FROM       users
+        INNER JOIN tokens
+			ON users.id = tokens.user_id
+        WHERE tokens.hash = $1  --<-- Note: this is potentially vulnerable to a timing attack, 
+            -- but if successful the attacker would only be able to retrieve a *hashed* token 
+            -- which would still require a brute-force attack to find the 26 character string
+            -- that has the same SHA-256 hash that was found from our database. 
+			AND tokens.scope = $2
+			AND datetime(tokens.expiry) > datetime($3)
+		`
+
+	// Create a slice containing the query args. Note, that we use the [:] operator to get a slice
+	// containing the token hash, since the driver does not support passing in an array.
+	// Also, we pass the current time as the value to check against the token expiry.
+	args := []interface{}{tokenHash[:], tokenScope, time.Now()}
+
+	var user model.User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// Execute the query, scanning the return values into a User struct. If no matching record
+	// is found we return an storage.ErrRecordNotFound error.
+	err := s.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.Hash,
+		&user.Activated,
+		&user.Dedupe,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, storage.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	// Return the matching user.
+	return &user, nil
+}
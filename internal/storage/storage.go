@@ -1,7 +1,6 @@
 package storage
 
 import (
-	"database/sql"
 	"errors"
 )
 
@@ -10,18 +9,13 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// Storage groups the repositories used by the handlers. The implementations live in the
+// backend packages, e.g. postgres.New and memory.New.
 type Storage struct {
 	Shortenings ShorteningsStorage
 	Permissions PermissionsStorage
 	Tokens      TokenStorage
 	Users       UserStorage
-}
-
-func New(db *sql.DB) Storage {
-	return Storage{
-		Shortenings: ShorteningsStorage{DB: db},
-		Permissions: PermissionsStorage{DB: db},
-		Tokens:      TokenStorage{DB: db},
-		Users:       UserStorage{DB: db},
-	}
+	Clicks      ClicksStorage
+	Domains     DomainsStorage
 }
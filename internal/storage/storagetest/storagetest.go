@@ -0,0 +1,339 @@
+// Package storagetest checks that a storage backend behaves like the others, so the handlers
+// work the same on every one of them.
+package storagetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yantay0/url-shortener/internal/model"
+	"github.com/yantay0/url-shortener/internal/storage"
+)
+
+// Run stores, reads, updates and deletes records through every repository of store, which must
+// be empty.
+func Run(t *testing.T, store storage.Storage) {
+	user := testUsers(t, store)
+	testTokens(t, store, user)
+	testPermissions(t, store, user)
+	testShortenings(t, store, user)
+	testArchiving(t, store, user)
+	testClicks(t, store, user)
+	testDomains(t, store, user)
+}
+
+func testUsers(t *testing.T, store storage.Storage) *model.User {
+	user := &model.User{Name: "Alice", Email: "alice@example.com", Activated: true}
+	user.Password.Hash = []byte("hash")
+
+	if err := store.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 || user.CreatedAt.IsZero() {
+		t.Fatalf("got user %+v, want the ID and creation time set", user)
+	}
+
+	duplicate := &model.User{Name: "Bob", Email: "alice@example.com"}
+	duplicate.Password.Hash = []byte("hash")
+	if err := store.Users.Insert(duplicate); !errors.Is(err, storage.ErrDuplicateEmail) {
+		t.Fatalf("got error %v for a taken email, want ErrDuplicateEmail", err)
+	}
+
+	user.Name = "Alice B."
+	user.Dedupe = true
+	if err := store.Users.Update(user); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.Users.GetByEmail("alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != user.ID || found.Name != "Alice B." || !found.Dedupe || !found.Activated || string(found.Password.Hash) != "hash" {
+		t.Fatalf("got user %+v, want %+v", found, user)
+	}
+
+	// The version seen before the update is stale.
+	found.Version--
+	if err := store.Users.Update(found); !errors.Is(err, storage.ErrEditConflict) {
+		t.Fatalf("got error %v updating a stale user, want ErrEditConflict", err)
+	}
+
+	if _, err := store.Users.GetByID(user.ID + 1000); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for an unknown user, want ErrRecordNotFound", err)
+	}
+
+	return user
+}
+
+func testTokens(t *testing.T, store storage.Storage, user *model.User) {
+	token, err := store.Tokens.New(user.ID, time.Hour, storage.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.Users.GetForToken(storage.ScopeAuthentication, token.Plaintext)
+	if err != nil || found.ID != user.ID {
+		t.Fatalf("got user %+v and error %v for the token, want user %d", found, err, user.ID)
+	}
+
+	if _, err := store.Users.GetForToken(storage.ScopeActivation, token.Plaintext); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for the token in another scope, want ErrRecordNotFound", err)
+	}
+
+	if err := store.Tokens.DeleteAllForUser(storage.ScopeAuthentication, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Users.GetForToken(storage.ScopeAuthentication, token.Plaintext); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for a deleted token, want ErrRecordNotFound", err)
+	}
+}
+
+func testPermissions(t *testing.T, store storage.Storage, user *model.User) {
+	if err := store.Permissions.AddRoleForUser(user.ID, model.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Permissions.AddForUser(user.ID, "permissions:read"); err != nil {
+		t.Fatal(err)
+	}
+
+	permissions, err := store.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !permissions.Include("shortenings:write") || !permissions.Include("permissions:read") {
+		t.Fatalf("got permissions %v, want the ones of the role and the direct grant", permissions)
+	}
+
+	if err := store.Permissions.AddRoleForUser(user.ID, "unknown"); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for an unknown role, want ErrRecordNotFound", err)
+	}
+
+	if err := store.Permissions.RemoveRoleForUser(user.ID, "unknown"); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v revoking an unknown role, want ErrRecordNotFound", err)
+	}
+	if err := store.Permissions.RemoveRoleForUser(user.ID, model.RoleViewer); !errors.Is(err, storage.ErrRoleNotGranted) {
+		t.Fatalf("got error %v revoking a role the user doesn't have, want ErrRoleNotGranted", err)
+	}
+
+	// The editor role is kept for the shortenings of the user.
+	if err := store.Permissions.AddRoleForUser(user.ID, model.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Permissions.RemoveRoleForUser(user.ID, model.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	roles, err := store.Permissions.GetRolesForUser(user.ID)
+	if err != nil || len(roles) != 1 || roles[0] != model.RoleEditor {
+		t.Fatalf("got roles %v and error %v, want only the editor role left", roles, err)
+	}
+}
+
+func testShortenings(t *testing.T, store storage.Storage, user *model.User) {
+	maxVisits := int64(1)
+	shortening := &model.Shortening{
+		Identifier:  "abcdefg",
+		OriginalURL: "https://example.com/",
+		UserID:      user.ID,
+		MaxVisits:   &maxVisits,
+	}
+
+	if err := store.Shortenings.SaveUserShortening(shortening); err != nil {
+		t.Fatal(err)
+	}
+	if shortening.Version != 1 || shortening.CreatedAt.IsZero() {
+		t.Fatalf("got shortening %+v, want version 1 and the creation time set", shortening)
+	}
+
+	taken := &model.Shortening{Identifier: "abcdefg", OriginalURL: "https://example.org/", UserID: user.ID}
+	if err := store.Shortenings.SaveUserShortening(taken); !errors.Is(err, storage.ErrIdentifierExists) {
+		t.Fatalf("got error %v for a taken identifier, want ErrIdentifierExists", err)
+	}
+
+	// Identifiers are unique per domain.
+	taken.Domain = "go.example.com"
+	if err := store.Shortenings.SaveUserShortening(taken); err != nil {
+		t.Fatal(err)
+	}
+
+	// A URL is deduplicated per user and domain.
+	urlHash := "hash"
+	deduped := &model.Shortening{Identifier: "deduped", OriginalURL: "https://example.com/", UserID: user.ID, URLHash: &urlHash}
+	if err := store.Shortenings.SaveUserShortening(deduped); err != nil {
+		t.Fatal(err)
+	}
+	again := &model.Shortening{Identifier: "again", OriginalURL: "https://example.com/", UserID: user.ID, URLHash: &urlHash}
+	if err := store.Shortenings.SaveUserShortening(again); !errors.Is(err, storage.ErrDuplicateURL) {
+		t.Fatalf("got error %v for a deduped URL, want ErrDuplicateURL", err)
+	}
+	again.Domain = "go.example.com"
+	if err := store.Shortenings.SaveUserShortening(again); err != nil {
+		t.Fatal(err)
+	}
+	found, err := store.Shortenings.GetByURLHash(user.ID, "go.example.com", urlHash)
+	if err != nil || found.Identifier != "again" {
+		t.Fatalf("got shortening %+v and error %v, want the one on the domain", found, err)
+	}
+
+	shortening.OriginalURL = "https://example.com/new"
+	if err := store.Shortenings.Update(shortening); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err = store.Shortenings.Get("", "abcdefg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.OriginalURL != "https://example.com/new" || found.Version != 2 || found.UserID != user.ID {
+		t.Fatalf("got shortening %+v, want the updated one", found)
+	}
+
+	found.Version = 1
+	if err := store.Shortenings.Update(found); !errors.Is(err, storage.ErrEditConflict) {
+		t.Fatalf("got error %v updating a stale shortening, want ErrEditConflict", err)
+	}
+
+	// The only allowed visit is counted once.
+	if _, err := store.Shortenings.GetOriginalUrl("", "abcdefg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Shortenings.CountVisit("", "abcdefg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Shortenings.CountVisit("", "abcdefg"); !errors.Is(err, storage.ErrLinkExpired) {
+		t.Fatalf("got error %v for a visit past max_visits, want ErrLinkExpired", err)
+	}
+	if _, err := store.Shortenings.GetOriginalUrl("", "abcdefg"); !errors.Is(err, storage.ErrLinkExpired) {
+		t.Fatalf("got error %v for a used up link, want ErrLinkExpired", err)
+	}
+
+	all, err := store.Shortenings.GetUserAllShortenings(user.ID)
+	if err != nil || len(all) != 4 {
+		t.Fatalf("got %d shortenings and error %v for the user, want 4", len(all), err)
+	}
+
+	if err := store.Shortenings.Delete("", "abcdefg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Shortenings.Get("", "abcdefg"); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for a deleted shortening, want ErrRecordNotFound", err)
+	}
+	if _, err := store.Shortenings.Get("go.example.com", "abcdefg"); err != nil {
+		t.Fatalf("got error %v, want the shortening on the other domain kept", err)
+	}
+}
+
+func testArchiving(t *testing.T, store storage.Storage, user *model.User) {
+	expired := time.Now().Add(-time.Hour)
+	shortening := &model.Shortening{Identifier: "expired", OriginalURL: "https://example.com/", UserID: user.ID, ExpiresAt: &expired}
+	if err := store.Shortenings.SaveUserShortening(shortening); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := store.Shortenings.ArchiveExpired(); err != nil || n == 0 {
+		t.Fatalf("got %d archived shortenings and error %v, want the expired one archived", n, err)
+	}
+
+	// Editing anything but the expiry or the visit limit keeps the link archived.
+	found, err := store.Shortenings.Get("", "expired")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found.OriginalURL = "https://example.com/new"
+	if err := store.Shortenings.Update(found); err != nil {
+		t.Fatal(err)
+	}
+	if found.ArchivedAt == nil {
+		t.Fatal("got the shortening unarchived by a new destination, want it archived")
+	}
+
+	found, err = store.Shortenings.Get("", "expired")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ArchivedAt == nil {
+		t.Fatal("got the stored shortening unarchived by a new destination, want it archived")
+	}
+
+	later := time.Now().Add(time.Hour)
+	found.ExpiresAt = &later
+	if err := store.Shortenings.Update(found); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err = store.Shortenings.Get("", "expired")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ArchivedAt != nil {
+		t.Fatal("got the shortening still archived after a new expiry, want it unarchived")
+	}
+}
+
+func testClicks(t *testing.T, store storage.Storage, user *model.User) {
+	shortening := &model.Shortening{Identifier: "clicked", OriginalURL: "https://example.com/", UserID: user.ID}
+	if err := store.Shortenings.SaveUserShortening(shortening); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	clicks := []*model.Click{
+		{Identifier: "clicked", ClickedAt: now, Referrer: "https://news.example.com/", UAFamily: "Firefox", Device: "desktop"},
+		{Identifier: "clicked", ClickedAt: now, UAFamily: "Safari", Device: "mobile"},
+		// Already counted on redirect.
+		{Identifier: "clicked", ClickedAt: now, UAFamily: "Safari", Device: "mobile", Counted: true},
+		// Clicks of deleted shortenings are skipped.
+		{Identifier: "deleted", ClickedAt: now, UAFamily: "Safari", Device: "mobile"},
+	}
+	if err := store.Clicks.InsertBatch(clicks); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.Shortenings.Get("", "clicked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Visits != 2 {
+		t.Fatalf("got %d visits, want the 2 clicks not counted on redirect", found.Visits)
+	}
+
+	stats, err := store.Clicks.Stats("", "clicked", "day", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 3 || len(stats.UAFamilies) != 2 || len(stats.Devices) != 2 {
+		t.Fatalf("got stats %+v, want 3 clicks from 2 browsers and devices", stats)
+	}
+}
+
+func testDomains(t *testing.T, store storage.Storage, user *model.User) {
+	domain := &model.Domain{Host: "go.example.com", UserID: user.ID, VerificationToken: "token"}
+	if err := store.Domains.Insert(domain); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Domains.GetByHost("go.example.com"); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for an unverified domain, want ErrRecordNotFound", err)
+	}
+
+	if err := store.Domains.MarkVerified(domain); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.Domains.GetByHost("go.example.com")
+	if err != nil || found.ID != domain.ID || found.VerifiedAt == nil {
+		t.Fatalf("got domain %+v and error %v, want the verified domain %d", found, err, domain.ID)
+	}
+
+	// The shortenings on the domain go with it.
+	if err := store.Domains.Delete(domain); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Domains.Get(domain.ID); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for a deleted domain, want ErrRecordNotFound", err)
+	}
+	if _, err := store.Shortenings.Get("go.example.com", "abcdefg"); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Fatalf("got error %v for a shortening of a deleted domain, want ErrRecordNotFound", err)
+	}
+}
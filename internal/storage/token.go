@@ -1,8 +1,6 @@
 package storage
 
 import (
-	"context"
-	"database/sql"
 	"time"
 
 	"github.com/yantay0/url-shortener/internal/model"
@@ -11,38 +9,22 @@ import (
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopeAPIKey         = "api_key"
 )
 
-type TokenStorage struct {
-	DB *sql.DB
-}
-
-func (s TokenStorage) New(userID int64, ttl time.Duration, scope string) (*model.Token, error) {
-	token, err := model.GenerateToken(userID, ttl, scope)
-	if err != nil {
-		return nil, err
-	}
-	err = s.Insert(token)
-	return token, err
-}
-
-func (s TokenStorage) Insert(token *model.Token) error {
-	query := `
-	INSERT INTO tokens (hash, user_id, expiry, scope)
-	VALUES ($1, $2, $3, $4)`
-	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	_, err := s.DB.ExecContext(ctx, query, args...)
-	return err
-}
-
-func (s TokenStorage) DeleteAllForUser(scope string, userID int64) error {
-	query := `
-	DELETE FROM tokens
-	WHERE scope = $1 AND user_id = $2`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	_, err := s.DB.ExecContext(ctx, query, scope, userID)
-	return err
+type TokenStorage interface {
+	// New generates a token for the user and inserts it.
+	New(userID int64, ttl time.Duration, scope string) (*model.Token, error)
+	// Insert sets the ID and creation time of the token and stores its permissions.
+	Insert(token *model.Token) error
+	DeleteAllForUser(scope string, userID int64) error
+	// GetForPlaintext returns the unexpired token of the scope with its permissions, or
+	// ErrRecordNotFound.
+	GetForPlaintext(scope, tokenPlaintext string) (*model.Token, error)
+	// GetAllForUser returns the unexpired tokens of the scope and user, oldest first.
+	GetAllForUser(scope string, userID int64) ([]*model.Token, error)
+	// DeleteForUser returns ErrRecordNotFound if the user has no token of the scope with the ID.
+	DeleteForUser(scope string, id, userID int64) error
+	// MarkUsed sets the last use of the token to now.
+	MarkUsed(token *model.Token) error
 }
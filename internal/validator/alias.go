@@ -0,0 +1,41 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// AliasRX matches the characters allowed in custom aliases. Only ASCII letters, digits, '-'
+	// and '_' are accepted, which rules out slashes, whitespace and unicode confusables.
+	AliasRX = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_-]*$")
+)
+
+type AliasRules struct {
+	MinLength int
+	MaxLength int
+	Reserved  []string // Compared case-insensitively
+}
+
+// Reserved reports whether alias is one of the reserved words, ignoring case.
+func Reserved(alias string, reserved ...string) bool {
+	for i := range reserved {
+		if strings.EqualFold(alias, reserved[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateAlias checks a user supplied identifier against the alias rules.
+func ValidateAlias(v *Validator, alias string, rules AliasRules) {
+	length := utf8.RuneCountInString(alias)
+
+	v.Check(strings.TrimSpace(alias) != "", "identifier", "must be provided")
+	v.Check(length >= rules.MinLength, "identifier", fmt.Sprintf("must be at least %d characters long", rules.MinLength))
+	v.Check(length <= rules.MaxLength, "identifier", fmt.Sprintf("must not be more than %d characters long", rules.MaxLength))
+	v.Check(Matches(alias, AliasRX), "identifier", "must start with a letter or digit and contain only letters, digits, '-' and '_'")
+	v.Check(!Reserved(alias, rules.Reserved...), "identifier", "is a reserved word")
+}
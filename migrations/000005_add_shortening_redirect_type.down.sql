@@ -0,0 +1 @@
+ALTER TABLE shortening DROP COLUMN IF EXISTS redirect_type;
\ No newline at end of file
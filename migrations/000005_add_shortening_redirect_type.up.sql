@@ -0,0 +1,2 @@
+-- A zero redirect_type means the server-wide default from the config is used.
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS redirect_type integer NOT NULL DEFAULT 0;
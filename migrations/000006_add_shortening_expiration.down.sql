@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS shortening_expires_at_idx;
+
+ALTER TABLE shortening DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE shortening DROP COLUMN IF EXISTS max_visits;
+ALTER TABLE shortening DROP COLUMN IF EXISTS expires_at;
\ No newline at end of file
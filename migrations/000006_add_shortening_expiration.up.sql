@@ -0,0 +1,5 @@
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS expires_at timestamp(0) with time zone;
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS max_visits integer;
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS archived_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS shortening_expires_at_idx ON shortening (expires_at) WHERE archived_at IS NULL;
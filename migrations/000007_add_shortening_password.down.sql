@@ -0,0 +1 @@
+ALTER TABLE shortening DROP COLUMN IF EXISTS password_hash;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS password_hash bytea;
\ No newline at end of file
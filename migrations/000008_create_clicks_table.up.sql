@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    id bigserial PRIMARY KEY,
+    identifier text NOT NULL REFERENCES shortening ON DELETE CASCADE,
+    clicked_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    referrer text NOT NULL DEFAULT '',
+    ua_family text NOT NULL DEFAULT '',
+    device text NOT NULL DEFAULT '',
+    ip_hash text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS clicks_identifier_clicked_at_idx ON clicks (identifier, clicked_at);
\ No newline at end of file
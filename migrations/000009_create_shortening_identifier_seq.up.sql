@@ -0,0 +1,3 @@
+-- Backs the sequence identifier strategy. It starts high enough to skip the shortest identifiers,
+-- which are left for custom aliases.
+CREATE SEQUENCE IF NOT EXISTS shortening_identifier_seq START WITH 1000000;
\ No newline at end of file
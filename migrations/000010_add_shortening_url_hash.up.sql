@@ -0,0 +1,2 @@
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS url_hash text;
+CREATE UNIQUE INDEX IF NOT EXISTS shortening_user_id_url_hash_idx ON shortening (user_id, url_hash);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS safety_verdict text NOT NULL DEFAULT '';
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS safety_reason text NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS shortening_next_check_at_idx;
+
+ALTER TABLE shortening DROP COLUMN IF EXISTS next_check_at;
+ALTER TABLE shortening DROP COLUMN IF EXISTS check_failures;
+ALTER TABLE shortening DROP COLUMN IF EXISTS healthy;
+ALTER TABLE shortening DROP COLUMN IF EXISTS last_status;
+ALTER TABLE shortening DROP COLUMN IF EXISTS last_checked_at;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS last_checked_at timestamp(0) with time zone;
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS last_status integer NOT NULL DEFAULT 0;
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS healthy boolean;
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS check_failures integer NOT NULL DEFAULT 0;
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS next_check_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS shortening_next_check_at_idx ON shortening (next_check_at) WHERE archived_at IS NULL;
\ No newline at end of file
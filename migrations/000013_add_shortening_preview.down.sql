@@ -0,0 +1,3 @@
+ALTER TABLE shortening DROP COLUMN IF EXISTS preview_image;
+ALTER TABLE shortening DROP COLUMN IF EXISTS preview_description;
+ALTER TABLE shortening DROP COLUMN IF EXISTS preview_title;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS preview_title text NOT NULL DEFAULT '';
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS preview_description text NOT NULL DEFAULT '';
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS preview_image text NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- Links on custom domains can't be kept once identifiers are globally unique again, their clicks
+-- are deleted by the cascade.
+DELETE FROM shortening WHERE domain <> '';
+
+DROP INDEX IF EXISTS clicks_domain_identifier_clicked_at_idx;
+CREATE INDEX IF NOT EXISTS clicks_identifier_clicked_at_idx ON clicks (identifier, clicked_at);
+
+ALTER TABLE clicks DROP CONSTRAINT IF EXISTS clicks_domain_identifier_fkey;
+ALTER TABLE shortening DROP CONSTRAINT IF EXISTS shortening_pkey;
+ALTER TABLE shortening ADD PRIMARY KEY (identifier);
+ALTER TABLE clicks ADD CONSTRAINT clicks_identifier_fkey
+    FOREIGN KEY (identifier) REFERENCES shortening ON DELETE CASCADE;
+
+ALTER TABLE clicks DROP COLUMN IF EXISTS domain;
+ALTER TABLE shortening DROP COLUMN IF EXISTS domain;
+
+DROP TABLE IF EXISTS domains;
\ No newline at end of file
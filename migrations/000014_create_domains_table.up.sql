@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS domains (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    host text UNIQUE NOT NULL,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    verification_token text NOT NULL,
+    verified_at timestamp(0) with time zone
+);
+
+-- Identifiers are unique per domain, the empty domain is the default host of the service.
+ALTER TABLE shortening ADD COLUMN IF NOT EXISTS domain text NOT NULL DEFAULT '';
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS domain text NOT NULL DEFAULT '';
+
+ALTER TABLE clicks DROP CONSTRAINT IF EXISTS clicks_identifier_fkey;
+ALTER TABLE shortening DROP CONSTRAINT IF EXISTS shortening_pkey;
+ALTER TABLE shortening ADD PRIMARY KEY (domain, identifier);
+ALTER TABLE clicks ADD CONSTRAINT clicks_domain_identifier_fkey
+    FOREIGN KEY (domain, identifier) REFERENCES shortening (domain, identifier) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS clicks_identifier_clicked_at_idx;
+CREATE INDEX IF NOT EXISTS clicks_domain_identifier_clicked_at_idx ON clicks (domain, identifier, clicked_at);
\ No newline at end of file
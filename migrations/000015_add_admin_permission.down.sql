@@ -0,0 +1 @@
+DELETE FROM permissions WHERE code = 'shortenings:admin';
\ No newline at end of file
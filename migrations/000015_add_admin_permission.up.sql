@@ -0,0 +1,2 @@
+INSERT INTO permissions (code)
+VALUES ('shortenings:admin');
\ No newline at end of file
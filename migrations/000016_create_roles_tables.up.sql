@@ -0,0 +1,30 @@
+CREATE UNIQUE INDEX IF NOT EXISTS permissions_code_idx ON permissions (code);
+INSERT INTO permissions (code)
+VALUES
+    ('permissions:read'),
+    ('permissions:write');
+CREATE TABLE IF NOT EXISTS roles (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS roles_permissions (
+    role_id bigint NOT NULL REFERENCES roles ON DELETE CASCADE,
+    permission_id bigint NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+CREATE TABLE IF NOT EXISTS users_roles (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    role_id bigint NOT NULL REFERENCES roles ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+INSERT INTO roles (name)
+VALUES
+    ('admin'),
+    ('editor'),
+    ('viewer');
+-- Admins get every permission, editors can read and write shortenings and viewers only read them.
+INSERT INTO roles_permissions
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE roles.name = 'admin'
+    OR (roles.name = 'editor' AND permissions.code IN ('shortenings:read', 'shortenings:write'))
+    OR (roles.name = 'viewer' AND permissions.code = 'shortenings:read');
\ No newline at end of file
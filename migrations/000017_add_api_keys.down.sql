@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS tokens_permissions;
+DELETE FROM tokens WHERE scope = 'api_key' OR expiry IS NULL;
+DROP INDEX IF EXISTS tokens_user_id_scope_idx;
+ALTER TABLE tokens ALTER COLUMN expiry SET NOT NULL;
+ALTER TABLE tokens DROP COLUMN IF EXISTS last_used_at;
+ALTER TABLE tokens DROP COLUMN IF EXISTS created_at;
+ALTER TABLE tokens DROP COLUMN IF EXISTS name;
+ALTER TABLE tokens DROP COLUMN IF EXISTS id;
\ No newline at end of file
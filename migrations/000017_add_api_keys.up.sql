@@ -0,0 +1,13 @@
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS id bigserial UNIQUE;
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS name text NOT NULL DEFAULT '';
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS created_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS last_used_at timestamp(0) with time zone;
+-- API keys don't have to expire.
+ALTER TABLE tokens ALTER COLUMN expiry DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS tokens_user_id_scope_idx ON tokens (user_id, scope);
+-- The permissions an API key is restricted to, a subset of the permissions of its user.
+CREATE TABLE IF NOT EXISTS tokens_permissions (
+    token_id bigint NOT NULL REFERENCES tokens (id) ON DELETE CASCADE,
+    permission_id bigint NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (token_id, permission_id)
+);
\ No newline at end of file
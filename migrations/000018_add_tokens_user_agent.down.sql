@@ -0,0 +1 @@
+ALTER TABLE tokens DROP COLUMN IF EXISTS user_agent;
\ No newline at end of file
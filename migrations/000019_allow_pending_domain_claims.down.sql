@@ -0,0 +1,10 @@
+-- Keeps the verified domain of each host, or else its oldest claim.
+DELETE FROM domains d
+WHERE d.verified_at IS NULL AND EXISTS (
+    SELECT 1 FROM domains o
+    WHERE o.host = d.host AND o.id <> d.id AND (o.verified_at IS NOT NULL OR o.id < d.id)
+);
+
+DROP INDEX IF EXISTS domains_host_user_id_idx;
+DROP INDEX IF EXISTS domains_host_verified_idx;
+ALTER TABLE domains ADD CONSTRAINT domains_host_key UNIQUE (host);
\ No newline at end of file
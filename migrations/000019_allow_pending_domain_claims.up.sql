@@ -0,0 +1,5 @@
+-- A host can be claimed by several users until one of them proves control over it, only a
+-- verified host is unique. Each user claims a host at most once.
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_host_key;
+CREATE UNIQUE INDEX IF NOT EXISTS domains_host_verified_idx ON domains (host) WHERE verified_at IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS domains_host_user_id_idx ON domains (host, user_id);
\ No newline at end of file
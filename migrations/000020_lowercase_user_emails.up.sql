@@ -0,0 +1,3 @@
+-- Emails are lowercased by the application since they are compared exactly, the existing ones
+-- are brought into the same form.
+UPDATE users SET email = lower(email);
\ No newline at end of file
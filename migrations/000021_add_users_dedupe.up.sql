@@ -0,0 +1,2 @@
+-- Default of the dedupe option for links the user creates without setting it.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS dedupe boolean NOT NULL DEFAULT false;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Fails if a user has deduped the same URL on several domains, those links have to be removed first.
+DROP INDEX IF EXISTS shortening_user_id_domain_url_hash_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS shortening_user_id_url_hash_idx ON shortening (user_id, url_hash);
\ No newline at end of file
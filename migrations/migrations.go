@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations so the binary can apply them itself. The files
+// in this directory are for Postgres, the ones in sqlite/ are their SQLite equivalents.
+package migrations
+
+import "embed"
+
+//go:embed *.sql sqlite/*.sql
+var FS embed.FS
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name TEXT NOT NULL,
+    -- COLLATE NOCASE gives the same case-insensitive uniqueness and lookups as citext.
+    email TEXT COLLATE NOCASE UNIQUE NOT NULL,
+    password_hash BLOB NOT NULL,
+    activated BOOLEAN NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS shortening (
+    identifier TEXT PRIMARY KEY,
+    original_url TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1,
+    visits INTEGER NOT NULL DEFAULT 0,
+    user_id INTEGER NOT NULL REFERENCES users(id)
+);
\ No newline at end of file
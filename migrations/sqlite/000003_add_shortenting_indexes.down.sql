@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS shortening_original_url_idx;
+DROP INDEX IF EXISTS shortening_user_id_idx;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- SQLite has no GIN indexes, plain b-tree indexes serve the same lookups.
+CREATE INDEX IF NOT EXISTS shortening_original_url_idx ON shortening (original_url);
+CREATE INDEX IF NOT EXISTS shortening_user_id_idx ON shortening (user_id);
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- A zero redirect_type means the server-wide default from the config is used.
+ALTER TABLE shortening ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE shortening ADD COLUMN expires_at DATETIME;
+ALTER TABLE shortening ADD COLUMN max_visits INTEGER;
+ALTER TABLE shortening ADD COLUMN archived_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS shortening_expires_at_idx ON shortening (expires_at) WHERE archived_at IS NULL;
\ No newline at end of file
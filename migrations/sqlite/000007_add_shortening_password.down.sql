@@ -0,0 +1 @@
+ALTER TABLE shortening DROP COLUMN password_hash;
\ No newline at end of file
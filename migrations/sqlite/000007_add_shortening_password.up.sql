@@ -0,0 +1 @@
+ALTER TABLE shortening ADD COLUMN password_hash BLOB;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    identifier TEXT NOT NULL REFERENCES shortening ON DELETE CASCADE,
+    clicked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    referrer TEXT NOT NULL DEFAULT '',
+    ua_family TEXT NOT NULL DEFAULT '',
+    device TEXT NOT NULL DEFAULT '',
+    ip_hash TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS clicks_identifier_clicked_at_idx ON clicks (identifier, clicked_at);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS shortening_identifier_seq;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- Backs the sequence identifier strategy, SQLite has no sequences. It starts high enough to skip
+-- the shortest identifiers, which are left for custom aliases.
+CREATE TABLE IF NOT EXISTS shortening_identifier_seq (
+    id INTEGER PRIMARY KEY AUTOINCREMENT
+);
+INSERT INTO sqlite_sequence (name, seq) VALUES ('shortening_identifier_seq', 999999);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS shortening_user_id_url_hash_idx;
+ALTER TABLE shortening DROP COLUMN url_hash;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE shortening DROP COLUMN safety_reason;
+ALTER TABLE shortening DROP COLUMN safety_verdict;
\ No newline at end of file
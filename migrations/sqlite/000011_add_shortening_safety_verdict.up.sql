@@ -0,0 +1,2 @@
+ALTER TABLE shortening ADD COLUMN safety_verdict TEXT NOT NULL DEFAULT '';
+ALTER TABLE shortening ADD COLUMN safety_reason TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS shortening_next_check_at_idx;
+
+ALTER TABLE shortening DROP COLUMN next_check_at;
+ALTER TABLE shortening DROP COLUMN check_failures;
+ALTER TABLE shortening DROP COLUMN healthy;
+ALTER TABLE shortening DROP COLUMN last_status;
+ALTER TABLE shortening DROP COLUMN last_checked_at;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE shortening ADD COLUMN last_checked_at DATETIME;
+ALTER TABLE shortening ADD COLUMN last_status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE shortening ADD COLUMN healthy BOOLEAN;
+ALTER TABLE shortening ADD COLUMN check_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE shortening ADD COLUMN next_check_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS shortening_next_check_at_idx ON shortening (next_check_at) WHERE archived_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE shortening DROP COLUMN preview_image;
+ALTER TABLE shortening DROP COLUMN preview_description;
+ALTER TABLE shortening DROP COLUMN preview_title;
\ No newline at end of file
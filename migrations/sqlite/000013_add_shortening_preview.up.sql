@@ -0,0 +1,3 @@
+ALTER TABLE shortening ADD COLUMN preview_title TEXT NOT NULL DEFAULT '';
+ALTER TABLE shortening ADD COLUMN preview_description TEXT NOT NULL DEFAULT '';
+ALTER TABLE shortening ADD COLUMN preview_image TEXT NOT NULL DEFAULT '';
\ No newline at end of file
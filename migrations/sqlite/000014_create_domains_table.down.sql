@@ -0,0 +1,64 @@
+-- Links on custom domains can't be kept once identifiers are globally unique again.
+CREATE TABLE shortening_old (
+    identifier TEXT PRIMARY KEY,
+    original_url TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1,
+    visits INTEGER NOT NULL DEFAULT 0,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    redirect_type INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    max_visits INTEGER,
+    archived_at DATETIME,
+    password_hash BLOB,
+    url_hash TEXT,
+    safety_verdict TEXT NOT NULL DEFAULT '',
+    safety_reason TEXT NOT NULL DEFAULT '',
+    last_checked_at DATETIME,
+    last_status INTEGER NOT NULL DEFAULT 0,
+    healthy BOOLEAN,
+    check_failures INTEGER NOT NULL DEFAULT 0,
+    next_check_at DATETIME,
+    preview_title TEXT NOT NULL DEFAULT '',
+    preview_description TEXT NOT NULL DEFAULT '',
+    preview_image TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO shortening_old (identifier, original_url, created_at, version, visits, user_id, redirect_type, expires_at, max_visits,
+    archived_at, password_hash, url_hash, safety_verdict, safety_reason, last_checked_at, last_status, healthy,
+    check_failures, next_check_at, preview_title, preview_description, preview_image)
+SELECT identifier, original_url, created_at, version, visits, user_id, redirect_type, expires_at, max_visits,
+    archived_at, password_hash, url_hash, safety_verdict, safety_reason, last_checked_at, last_status, healthy,
+    check_failures, next_check_at, preview_title, preview_description, preview_image
+FROM shortening
+WHERE domain = '';
+
+CREATE TABLE clicks_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    identifier TEXT NOT NULL REFERENCES shortening_old ON DELETE CASCADE,
+    clicked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    referrer TEXT NOT NULL DEFAULT '',
+    ua_family TEXT NOT NULL DEFAULT '',
+    device TEXT NOT NULL DEFAULT '',
+    ip_hash TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO clicks_old (id, identifier, clicked_at, referrer, ua_family, device, ip_hash)
+SELECT id, identifier, clicked_at, referrer, ua_family, device, ip_hash
+FROM clicks
+WHERE domain = '';
+
+DROP TABLE clicks;
+DROP TABLE shortening;
+
+ALTER TABLE shortening_old RENAME TO shortening;
+ALTER TABLE clicks_old RENAME TO clicks;
+
+CREATE INDEX IF NOT EXISTS shortening_original_url_idx ON shortening (original_url);
+CREATE INDEX IF NOT EXISTS shortening_user_id_idx ON shortening (user_id);
+CREATE INDEX IF NOT EXISTS shortening_expires_at_idx ON shortening (expires_at) WHERE archived_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS shortening_user_id_url_hash_idx ON shortening (user_id, url_hash);
+CREATE INDEX IF NOT EXISTS shortening_next_check_at_idx ON shortening (next_check_at) WHERE archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS clicks_identifier_clicked_at_idx ON clicks (identifier, clicked_at);
+
+DROP TABLE IF EXISTS domains;
\ No newline at end of file
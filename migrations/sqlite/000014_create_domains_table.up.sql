@@ -0,0 +1,76 @@
+CREATE TABLE IF NOT EXISTS domains (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    host TEXT UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+    verification_token TEXT NOT NULL,
+    verified_at DATETIME
+);
+
+-- SQLite can't change a primary key, so shortening and clicks are rebuilt with the domain in their
+-- keys. The empty domain is the default host of the service. The old clicks table is dropped
+-- before shortening, otherwise dropping shortening would cascade to the clicks.
+CREATE TABLE shortening_new (
+    identifier TEXT NOT NULL,
+    domain TEXT NOT NULL DEFAULT '',
+    original_url TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1,
+    visits INTEGER NOT NULL DEFAULT 0,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    redirect_type INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    max_visits INTEGER,
+    archived_at DATETIME,
+    password_hash BLOB,
+    url_hash TEXT,
+    safety_verdict TEXT NOT NULL DEFAULT '',
+    safety_reason TEXT NOT NULL DEFAULT '',
+    last_checked_at DATETIME,
+    last_status INTEGER NOT NULL DEFAULT 0,
+    healthy BOOLEAN,
+    check_failures INTEGER NOT NULL DEFAULT 0,
+    next_check_at DATETIME,
+    preview_title TEXT NOT NULL DEFAULT '',
+    preview_description TEXT NOT NULL DEFAULT '',
+    preview_image TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (domain, identifier)
+);
+
+INSERT INTO shortening_new (identifier, original_url, created_at, version, visits, user_id, redirect_type, expires_at, max_visits,
+    archived_at, password_hash, url_hash, safety_verdict, safety_reason, last_checked_at, last_status, healthy,
+    check_failures, next_check_at, preview_title, preview_description, preview_image)
+SELECT identifier, original_url, created_at, version, visits, user_id, redirect_type, expires_at, max_visits,
+    archived_at, password_hash, url_hash, safety_verdict, safety_reason, last_checked_at, last_status, healthy,
+    check_failures, next_check_at, preview_title, preview_description, preview_image
+FROM shortening;
+
+CREATE TABLE clicks_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    domain TEXT NOT NULL DEFAULT '',
+    identifier TEXT NOT NULL,
+    clicked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    referrer TEXT NOT NULL DEFAULT '',
+    ua_family TEXT NOT NULL DEFAULT '',
+    device TEXT NOT NULL DEFAULT '',
+    ip_hash TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (domain, identifier) REFERENCES shortening_new (domain, identifier) ON DELETE CASCADE
+);
+
+INSERT INTO clicks_new (id, identifier, clicked_at, referrer, ua_family, device, ip_hash)
+SELECT id, identifier, clicked_at, referrer, ua_family, device, ip_hash
+FROM clicks;
+
+DROP TABLE clicks;
+DROP TABLE shortening;
+
+-- Renaming also rewrites the foreign key of clicks_new.
+ALTER TABLE shortening_new RENAME TO shortening;
+ALTER TABLE clicks_new RENAME TO clicks;
+
+CREATE INDEX IF NOT EXISTS shortening_original_url_idx ON shortening (original_url);
+CREATE INDEX IF NOT EXISTS shortening_user_id_idx ON shortening (user_id);
+CREATE INDEX IF NOT EXISTS shortening_expires_at_idx ON shortening (expires_at) WHERE archived_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS shortening_user_id_url_hash_idx ON shortening (user_id, url_hash);
+CREATE INDEX IF NOT EXISTS shortening_next_check_at_idx ON shortening (next_check_at) WHERE archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS clicks_domain_identifier_clicked_at_idx ON clicks (domain, identifier, clicked_at);
\ No newline at end of file
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS tokens_permissions;
+CREATE TABLE tokens_old (
+    hash BLOB PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+    expiry DATETIME NOT NULL,
+    scope TEXT NOT NULL
+);
+INSERT INTO tokens_old (hash, user_id, expiry, scope)
+SELECT hash, user_id, expiry, scope FROM tokens WHERE scope <> 'api_key' AND expiry IS NOT NULL;
+DROP TABLE tokens;
+ALTER TABLE tokens_old RENAME TO tokens;
\ No newline at end of file
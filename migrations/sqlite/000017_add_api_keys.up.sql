@@ -0,0 +1,23 @@
+-- SQLite can't add an autoincrement column or drop a NOT NULL constraint, so tokens is rebuilt
+-- with an ID and an optional expiry for API keys.
+CREATE TABLE tokens_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    hash BLOB NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expiry DATETIME,
+    last_used_at DATETIME,
+    scope TEXT NOT NULL
+);
+INSERT INTO tokens_new (hash, user_id, expiry, scope)
+SELECT hash, user_id, expiry, scope FROM tokens;
+DROP TABLE tokens;
+ALTER TABLE tokens_new RENAME TO tokens;
+CREATE INDEX IF NOT EXISTS tokens_user_id_scope_idx ON tokens (user_id, scope);
+-- The permissions an API key is restricted to, a subset of the permissions of its user.
+CREATE TABLE IF NOT EXISTS tokens_permissions (
+    token_id INTEGER NOT NULL REFERENCES tokens (id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (token_id, permission_id)
+);
\ No newline at end of file
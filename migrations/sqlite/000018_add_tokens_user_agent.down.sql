@@ -0,0 +1 @@
+ALTER TABLE tokens DROP COLUMN user_agent;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- The User-Agent header of the login that created a session token.
+ALTER TABLE tokens ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- Keeps the verified domain of each host, or else its oldest claim.
+DELETE FROM domains
+WHERE verified_at IS NULL AND EXISTS (
+    SELECT 1 FROM domains o
+    WHERE o.host = domains.host AND o.id <> domains.id AND (o.verified_at IS NOT NULL OR o.id < domains.id)
+);
+CREATE TABLE domains_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    host TEXT UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+    verification_token TEXT NOT NULL,
+    verified_at DATETIME
+);
+INSERT INTO domains_old (id, created_at, host, user_id, verification_token, verified_at)
+SELECT id, created_at, host, user_id, verification_token, verified_at FROM domains;
+DROP TABLE domains;
+ALTER TABLE domains_old RENAME TO domains;
\ No newline at end of file
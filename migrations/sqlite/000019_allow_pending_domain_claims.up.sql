@@ -0,0 +1,16 @@
+-- SQLite can't drop a UNIQUE column constraint, so domains is rebuilt. A host can be claimed by
+-- several users until one of them proves control over it, only a verified host is unique.
+CREATE TABLE domains_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    host TEXT NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+    verification_token TEXT NOT NULL,
+    verified_at DATETIME
+);
+INSERT INTO domains_new (id, created_at, host, user_id, verification_token, verified_at)
+SELECT id, created_at, host, user_id, verification_token, verified_at FROM domains;
+DROP TABLE domains;
+ALTER TABLE domains_new RENAME TO domains;
+CREATE UNIQUE INDEX IF NOT EXISTS domains_host_verified_idx ON domains (host) WHERE verified_at IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS domains_host_user_id_idx ON domains (host, user_id);
\ No newline at end of file
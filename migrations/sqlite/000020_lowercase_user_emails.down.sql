@@ -0,0 +1 @@
+-- The original case of the emails isn't kept, lowercase emails are valid either way.
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN dedupe;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Default of the dedupe option for links the user creates without setting it.
+ALTER TABLE users ADD COLUMN dedupe BOOLEAN NOT NULL DEFAULT 0;
\ No newline at end of file
@@ -0,0 +1 @@
+-- The granted editor roles can't be told apart from the ones granted by admins, they're kept.
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- Creating links needs shortenings:write since the roles were introduced, which the viewer role
+-- given to new users until now and the direct shortenings:read grant of older users lack. Users
+-- without the editor or admin role get the editor role, the default of new users from now on.
+INSERT INTO users_roles (user_id, role_id)
+SELECT users.id, roles.id FROM users, roles
+WHERE roles.name = 'editor' AND NOT EXISTS (
+    SELECT 1 FROM users_roles
+    INNER JOIN roles granted ON granted.id = users_roles.role_id
+    WHERE users_roles.user_id = users.id AND granted.name IN ('editor', 'admin')
+);
\ No newline at end of file
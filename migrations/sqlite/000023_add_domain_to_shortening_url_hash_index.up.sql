@@ -0,0 +1,4 @@
+-- A user may shorten the same URL in dedupe mode once per domain, the link on one domain is no
+-- use on another one.
+DROP INDEX IF EXISTS shortening_user_id_url_hash_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS shortening_user_id_domain_url_hash_idx ON shortening (user_id, domain, url_hash);
\ No newline at end of file